@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/cleanscene.flights/lib/chart"
+	"github.com/cleanscene.flights/lib/output"
+)
+
+var fieldUnits = map[string]string{
+	"offset":   "€",
+	"carbon":   "kg CO2",
+	"fuel":     "litres of fuel",
+	"distance": "km",
+	"flights":  "flights",
+}
+
+// A flight's share of a field's total.
+func flightValue(f output.Flight, field string) float64 {
+	switch field {
+	case "offset":
+		return f.OffsetEUR
+	case "fuel":
+		return f.FuelL
+	case "distance":
+		return float64(f.DistanceKm)
+	case "flights":
+		return float64(countFlight(f))
+	}
+	return f.CarbonKg
+}
+
+/*
+Draws the field, carbon by default, as svg figures in dir: how it is
+spread over artists, the top n artists, the total by month and a Lorenz
+curve of how unequally it is shared.
+*/
+func drawCharts(files []string, field string, n, bins int, dir string) error {
+	if field == "" {
+		field = "carbon"
+	}
+	unit, ok := fieldUnits[field]
+	if !ok {
+		return fmt.Errorf("no field %q to chart", field)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	var stats []stat
+	monthly := make(map[string]float64)
+	for _, file := range files {
+		flights, err := readFlights(file)
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		s := stat{name: artistName(file)}
+		for _, f := range flights {
+			v := flightValue(f, field)
+			s.val += v
+			if d, err := time.Parse("2006-01-02", f.Date); err == nil {
+				monthly[d.Format("2006-01")] += v
+			}
+		}
+		stats = append(stats, s)
+	}
+	if len(stats) == 0 {
+		return fmt.Errorf("no flights to chart")
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].val != stats[j].val {
+			return stats[i].val > stats[j].val
+		}
+		return stats[i].name < stats[j].name
+	})
+
+	var (
+		values []float64
+		labels []string
+		top    []float64
+	)
+	for i, s := range stats {
+		values = append(values, s.val)
+		if i < n {
+			labels = append(labels, s.name)
+			top = append(top, s.val)
+		}
+	}
+	months, totals := monthSeries(monthly)
+
+	charts := map[string]string{
+		field + "-distribution.svg": chart.Histogram(fmt.Sprintf("Artists by %s", field), values, bins, unit),
+		field + "-top.svg":          chart.Bars(fmt.Sprintf("Top %d artists by %s", len(top), field), labels, top, unit),
+		field + "-monthly.svg":      chart.Line(fmt.Sprintf("%s by month", field), months, []chart.Series{{Name: field, Values: totals}}, unit),
+		field + "-lorenz.svg":       chart.Lorenz(fmt.Sprintf("Share of %s", field), values),
+	}
+	for name, svg := range charts {
+		fName := filepath.Join(dir, name)
+		if err := chart.Write(fName, svg); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote %s\n", fName)
+	}
+	return nil
+}
+
+// Every month from the first to the last flown, months without flights as 0.
+func monthSeries(monthly map[string]float64) ([]string, []float64) {
+	var keys []string
+	for k := range monthly {
+		keys = append(keys, k)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	sort.Strings(keys)
+	first, _ := time.Parse("2006-01", keys[0])
+	last, _ := time.Parse("2006-01", keys[len(keys)-1])
+	var (
+		labels []string
+		values []float64
+	)
+	for m := first; !m.After(last); m = m.AddDate(0, 1, 0) {
+		labels = append(labels, m.Format("2006-01"))
+		values = append(values, monthly[m.Format("2006-01")])
+	}
+	return labels, values
+}
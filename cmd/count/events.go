@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cleanscene.flights/lib/atmos"
+)
+
+/*
+Footprint of one event or venue, summed over every artist playing it. An
+event is one night's listing, the same title at the same venue on another
+night is counted as another event, a venue's figures cover all its nights.
+*/
+type eventStat struct {
+	event    string
+	date     string
+	venue    string
+	artists  map[string]bool
+	nights   map[string]bool
+	gigs     int
+	carbon   float64
+	capacity int
+}
+
+// Carbon per ticket over every night counted, taking each one as sold out.
+func (s eventStat) perAttendee() float64 {
+	if s.capacity == 0 || len(s.nights) == 0 {
+		return 0
+	}
+	return s.carbon / float64(s.capacity*len(s.nights))
+}
+
+/*
+countEvents ranks events, or venues when groupBy is "venue", by the carbon of
+the flights their artists took to play them. Each flight is attributed to the
+gigs it serves, see atmos.Output.AttributedTo, so a flight between two gigs
+is split between them. With a capacity file of name,capacity rows, naming
+an event or venue, the carbon per attendee is worked out too, a venue's
+capacity counted once for every night it had a gig.
+*/
+func countEvents(files []string, groupBy, capacityFile string, n int) error {
+	capacities := make(map[string]int)
+	if capacityFile != "" {
+		var err error
+		if capacities, err = readCapacities(capacityFile); err != nil {
+			return err
+		}
+	}
+	stats := make(map[string]*eventStat)
+	seen := make(map[string]bool)
+	for _, file := range files {
+		artist := artistName(file)
+		outputs, err := readAttributed(file)
+		if err != nil {
+			fmt.Printf("%s: %v\n", file, err)
+			continue
+		}
+		for _, o := range outputs {
+			for gig, share := range o.AttributedTo() {
+				night := gig.Date.Format("2006-01-02")
+				key, event, date := gig.Venue, "", ""
+				if groupBy != "venue" {
+					key, event, date = gig.Title+"\x00"+gig.Venue+"\x00"+night, gig.Title, night
+				}
+				stat, ok := stats[key]
+				if !ok {
+					stat = &eventStat{event: event, date: date, venue: gig.Venue, artists: make(map[string]bool), nights: make(map[string]bool)}
+					stats[key] = stat
+				}
+				stat.artists[artist] = true
+				stat.nights[night] = true
+				stat.carbon += share * o.CarbonOutput
+				if gigKey := artist + gig.Date.String() + key; !seen[gigKey] {
+					seen[gigKey] = true
+					stat.gigs++
+				}
+			}
+		}
+	}
+
+	var ranked []eventStat
+	for _, stat := range stats {
+		if c, ok := capacities[strings.ToLower(stat.event)]; ok && stat.event != "" {
+			stat.capacity = c
+		} else {
+			stat.capacity = capacities[strings.ToLower(stat.venue)]
+		}
+		ranked = append(ranked, *stat)
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		return ranked[i].carbon > ranked[j].carbon
+	})
+
+	fmt.Printf("The top %d %ss by artist flight carbon are...\n", n, groupBy)
+	for i, stat := range ranked {
+		if i == n {
+			break
+		}
+		name := stat.venue
+		if stat.event != "" {
+			name = fmt.Sprintf("%s @ %s on %s", stat.event, stat.venue, stat.date)
+		}
+		fmt.Printf("%s: %f kg from %d artists\n", name, stat.carbon, len(stat.artists))
+	}
+	return writeEvents(ranked, fmt.Sprintf("./output/stats/%ss.csv", groupBy))
+}
+
+// Reads the carbon and gigs of each flight in an artist's flight file.
+func readAttributed(fName string) ([]atmos.Output, error) {
+	var outputs []atmos.Output
+	flights, err := readFlights(fName)
+	if err != nil {
+		return outputs, err
+	}
+	attributed := false
+	for _, f := range flights {
+		o := f.Output()
+		attributed = attributed || !o.From.IsZero() || !o.To.IsZero()
+		outputs = append(outputs, o)
+	}
+	if len(outputs) > 0 && !attributed {
+		return outputs, fmt.Errorf("flights are not attributed to gigs, rerun fly")
+	}
+	return outputs, nil
+}
+
+// Capacities keyed by lower case event or venue name.
+func readCapacities(fName string) (map[string]int, error) {
+	capacities := make(map[string]int)
+	file, err := os.Open(fName)
+	if err != nil {
+		return capacities, err
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return capacities, err
+	}
+	for i, record := range records {
+		if len(record) < 2 {
+			return capacities, fmt.Errorf("%s:%d: expected name,capacity", fName, i+1)
+		}
+		capacity, err := strconv.Atoi(strings.TrimSpace(record[1]))
+		if err != nil {
+			// Skip the header
+			if i == 0 {
+				continue
+			}
+			return capacities, fmt.Errorf("%s:%d: %v", fName, i+1, err)
+		}
+		capacities[strings.ToLower(strings.TrimSpace(record[0]))] = capacity
+	}
+	return capacities, nil
+}
+
+var eventHeaders = []string{"Event", "Date", "Venue", "Artists", "Gigs", "Nights", "Carbon (kg)", "Capacity", "Carbon per attendee (kg)"}
+
+func writeEvents(stats []eventStat, fName string) error {
+	csvfile, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer csvfile.Close()
+	csvwriter := csv.NewWriter(csvfile)
+	csvwriter.Write(eventHeaders)
+	for _, stat := range stats {
+		capacity, perAttendee := "", ""
+		if stat.capacity > 0 {
+			capacity = fmt.Sprintf("%d", stat.capacity)
+			perAttendee = fmt.Sprintf("%f", stat.perAttendee())
+		}
+		csvwriter.Write([]string{
+			stat.event,
+			stat.date,
+			stat.venue,
+			fmt.Sprintf("%d", len(stat.artists)),
+			fmt.Sprintf("%d", stat.gigs),
+			fmt.Sprintf("%d", len(stat.nights)),
+			fmt.Sprintf("%f", stat.carbon),
+			capacity,
+			perAttendee,
+		})
+	}
+	csvwriter.Flush()
+	return csvwriter.Error()
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"encoding/csv"
 	"flag"
 	"fmt"
@@ -9,75 +8,84 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
-	"strconv"
 	"strings"
-)
 
-func parseEu(eu string) float64 {
-	euNum := strings.Replace(eu, "€", "", -1)
-	f, _ := strconv.ParseFloat(euNum, 64)
-	return f
-}
-func parseKg(kg string) float64 {
-	kgNum := strings.Replace(kg, "kg", "", -1)
-	kgNumNoSp := strings.Replace(kgNum, " ", "", -1)
-	f, _ := strconv.ParseFloat(kgNumNoSp, 64)
-	return f
-}
-func parseL(L string) float64 {
-	LNum := strings.Replace(L, "L", "", -1)
-	LNumNS := strings.Replace(LNum, " ", "", -1)
-	f, _ := strconv.ParseFloat(LNumNS, 64)
-	return f
-}
-func parseKm(km string) float64 {
-	kmNum := strings.Replace(km, "km", "", -1)
-	kmNumNS := strings.Replace(kmNum, " ", "", -1)
-	f, _ := strconv.ParseFloat(kmNumNS, 64)
-	return f
-}
+	"github.com/cleanscene.flights/lib/output"
+	"github.com/cleanscene.flights/lib/store"
+)
 
-func countFlights(flight []string) int {
-	dep, arr := flight[0], flight[1]
-	if dep == arr {
+// Flights between two different airports with every figure known, the rest
+// being placeholders for gigs that could not be flown to.
+func countFlight(f output.Flight) int {
+	if f.Departure == f.Arrival {
 		return 0
 	}
-	if parseEu(flight[3]) == 0 || parseKg(flight[4]) == 0 || parseL(flight[5]) == 0 || parseKm(flight[6]) == 0 {
+	if f.OffsetEUR == 0 || f.CarbonKg == 0 || f.FuelL == 0 || f.DistanceKm == 0 {
 		return 0
 	}
 	return 1
-
 }
 
 func getTotalNumbers(fileName string) (float64, float64, float64, float64, int) {
-	file, err := os.Open(fileName)
+	var totalEu, totalCarbon, totalFuel, totalDistance float64
+	var totalFlights int
+
+	flights, err := readFlights(fileName)
 	if err != nil {
 		fmt.Println(err.Error())
 	}
-	defer file.Close()
-	var totalEu, totalCarbon, totalFuel, totalDistance float64
-	var totalFlights int
+	for _, f := range flights {
+		totalEu = totalEu + f.OffsetEUR
+		totalCarbon = totalCarbon + f.CarbonKg
+		totalFuel = totalFuel + f.FuelL
+		totalDistance = totalDistance + float64(f.DistanceKm)
+		totalFlights = totalFlights + countFlight(f)
+	}
+	return totalEu, totalCarbon, totalFuel, totalDistance, totalFlights
 
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		arr := strings.Split(scanner.Text(), ",")
-		if len(arr) < 7 {
-			continue
-		}
-		euoffset := parseEu(arr[3])
-		totalEu = totalEu + euoffset
-		carbon := parseKg(arr[4])
-		totalCarbon = totalCarbon + carbon
-		fuel := parseL(arr[5])
-		totalFuel = totalFuel + fuel
-		distance := parseKm(arr[6])
-		totalDistance = totalDistance + distance
-		numFlights := countFlights(arr)
-		totalFlights = totalFlights + numFlights
+}
+
+// Artist of a flight file, named after them.
+func fileArtist(fileName string) string {
+	return strings.TrimSuffix(filepath.Base(fileName), filepath.Ext(fileName))
+}
+
+// Flights are read from each artist's file, or with -store from the store,
+// the files then being the artists' names.
+var (
+	readFlights = output.ReadFlights
+	artistName  = fileArtist
+)
 
+// Reads the scenario's flights from the store for the counts to work on.
+func fromStore(db store.Store, scenario string) ([]string, error) {
+	flights, err := db.Flights(scenario)
+	if err != nil {
+		return nil, err
 	}
-	return totalEu, totalCarbon, totalFuel, totalDistance, totalFlights
+	if len(flights) == 0 {
+		return nil, fmt.Errorf("no flights stored for scenario %q", scenario)
+	}
+	var names []string
+	for name := range flights {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	readFlights = func(name string) ([]output.Flight, error) { return flights[name], nil }
+	artistName = func(name string) string { return name }
+	return names, nil
+}
 
+// Runs a SELECT against the store, printing the result as csv.
+func query(db store.Store, sql string) error {
+	columns, rows, err := db.Query(sql)
+	if err != nil {
+		return err
+	}
+	csvwriter := csv.NewWriter(os.Stdout)
+	csvwriter.Write(columns)
+	csvwriter.WriteAll(rows)
+	return csvwriter.Error()
 }
 
 func countAll(files []string) {
@@ -148,9 +156,7 @@ func countTopN(files []string, field string, n int) {
 	var totalVal float64
 	for _, stat := range stats[:n] {
 		totalVal = totalVal + stat.val
-		pathName := strings.Split(stat.name, "/")
-		name := strings.Replace(pathName[len(pathName)-1], ".csv", "", -1)
-		fmt.Printf("%s: %f\n", name, stat.val)
+		fmt.Printf("%s: %f\n", artistName(stat.name), stat.val)
 	}
 	fmt.Printf("Totaling at: %f", totalVal)
 	compare(stats, totalVal)
@@ -201,9 +207,7 @@ func countAllOrdered(files []string, orderBy string) {
 	var stats = make([]orderedStat, 0)
 	for _, file := range files {
 		eu, car, _, dist, flights := getTotalNumbers(file)
-		pathName := strings.Split(file, "/")
-		name := strings.Replace(pathName[len(pathName)-1], ".csv", "", -1)
-		stats = append(stats, orderedStat{name: name, flights: float64(flights), carbon: car, distance: dist, offset: eu})
+		stats = append(stats, orderedStat{name: artistName(file), flights: float64(flights), carbon: car, distance: dist, offset: eu})
 	}
 	sort.Slice(stats, func(i, j int) bool {
 		return int(stats[i].carbon) > int(stats[j].carbon)
@@ -216,18 +220,45 @@ var param = flag.String("param", "", "what you want to count")
 var countN = flag.Int("N", 10, "number of top artists you want the count for")
 var field = flag.String("field", "", "what you want the total of")
 var orderBy = flag.String("order-by", "carbon", "by which number should this totals list be ordered")
+var groupBy = flag.String("group-by", "event", "group the events footprint by event or venue")
+var storeFile = flag.String("store", "", "optional sqlite store written by fly -store.file, to count from instead of the csv files")
+var scenario = flag.String("scenario", "", "scenario in the store to count, e.g. the tour year")
+var sqlQuery = flag.String("sql", "", "SELECT to run against the store with -param sql, e.g. to join emissions with venues")
+var capacityFile = flag.String("capacity", "", "optional csv of event or venue name,capacity for per attendee figures")
+var chartDir = flag.String("chart.dir", "./output/stats/charts", "directory -param charts writes its svg figures to")
+var bins = flag.Int("bins", 20, "about how many bins the -param charts distribution is counted into")
 
 func main() {
 	var files []string
 	flag.Parse()
 	root := "./output/artist-pages/done"
 
-	_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		if strings.Contains(path, "csv") {
-			files = append(files, path)
+	if *storeFile != "" {
+		db, err := store.OpenReadOnly(*storeFile)
+		if err != nil {
+			log.Fatal(err)
 		}
-		return nil
-	})
+		defer db.Close()
+		if *param == "sql" {
+			if err := query(db, *sqlQuery); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		if *scenario == "" {
+			log.Fatal("missing scenario to count from the store")
+		}
+		if files, err = fromStore(db, *scenario); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		_ = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if strings.Contains(path, "csv") || output.IsJSONLines(path) {
+				files = append(files, path)
+			}
+			return nil
+		})
+	}
 
 	switch *param {
 	case "total":
@@ -236,6 +267,16 @@ func main() {
 		countTopN(files, *field, *countN)
 	case "top-all-ordered":
 		countAllOrdered(files, *orderBy)
+	case "events":
+		if err := countEvents(files, *groupBy, *capacityFile, *countN); err != nil {
+			log.Fatal(err)
+		}
+	case "charts":
+		if err := drawCharts(files, *field, *countN, *bins, *chartDir); err != nil {
+			log.Fatal(err)
+		}
+	case "sql":
+		log.Fatal("sql queries need a -store to run against")
 	default:
 		log.Fatal("nothing to count :/")
 	}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/source"
+)
+
+// Bumped whenever the artifact layout changes, older artifacts have to be
+// made again by rerunning the stage that wrote them.
+const artifactVersion = 2
+
+/*
+An artifact is what a stage wrote for one artist, read by the next stage
+from <work.dir>/<stage>/<artist>.json. Each stage fills in its own part:
+
+	scrape    listings, the gigs from each event source
+	resolve   events, geocoded and merged, and how many were scraped
+	plan      trips and tours
+	emit      outputs, the emissions of each flight
+
+Excluded artists and those a stage failed for are carried through to the
+report, so the audit accounts for everyone.
+*/
+type artifact struct {
+	Version  int              `json:"version"`
+	Stage    string           `json:"stage"`
+	Artist   ra.Artist        `json:"artist"`
+	Error    string           `json:"error,omitempty"`
+	Listings []source.Listing `json:"listings,omitempty"`
+	Scraped  int              `json:"scraped,omitempty"`
+	Events   crawler.Events   `json:"events,omitempty"`
+	Trips    flight.Trips     `json:"trips,omitempty"`
+	Tours    []flight.Tour    `json:"tours,omitempty"`
+	Outputs  []atmos.Output   `json:"outputs,omitempty"`
+}
+
+// Excluded artists and those an earlier stage failed for are passed on untouched.
+func (a artifact) skip() bool {
+	return a.Artist.Excluded != "" || a.Error != ""
+}
+
+func (a *artifact) fail(err error) {
+	errCheck(err)
+	a.Error = err.Error()
+}
+
+func artifactPath(stage, artist string) string {
+	return filepath.Join(*workDir, stage, artist+".json")
+}
+
+func writeArtifact(stage string, a artifact) error {
+	a.Version, a.Stage = artifactVersion, stage
+	fName := artifactPath(stage, a.Artist.Name)
+	if err := os.MkdirAll(filepath.Dir(fName), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(a, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(fName, data, 0644)
+}
+
+// Removes what a stage wrote for artists since dropped from the registry.
+func prune(stage string, artists []string) error {
+	keep := make(map[string]bool)
+	for _, artist := range artists {
+		keep[artifactPath(stage, artist)] = true
+	}
+	files, err := filepath.Glob(filepath.Join(*workDir, stage, "*.json"))
+	if err != nil {
+		return err
+	}
+	for _, fName := range files {
+		if !keep[fName] {
+			if err := os.Remove(fName); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func readArtifact(fName string) (artifact, error) {
+	var a artifact
+	data, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return a, err
+	}
+	// The version first, older layouts may not decode at all
+	var header struct {
+		Version int    `json:"version"`
+		Stage   string `json:"stage"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return a, fmt.Errorf("%s: %v", fName, err)
+	}
+	if header.Version != artifactVersion {
+		return a, fmt.Errorf("%s: artifact version %d, expected %d, rerun %s", fName, header.Version, artifactVersion, header.Stage)
+	}
+	if err := json.Unmarshal(data, &a); err != nil {
+		return a, fmt.Errorf("%s: %v", fName, err)
+	}
+	return a, nil
+}
+
+// Every artist's artifact from a stage, in name order.
+func readArtifacts(stage string) ([]artifact, error) {
+	var artifacts []artifact
+	files, err := filepath.Glob(filepath.Join(*workDir, stage, "*.json"))
+	if err != nil {
+		return artifacts, err
+	}
+	if len(files) == 0 {
+		return artifacts, fmt.Errorf("no %s artifacts in %s, run fly %s first", stage, *workDir, stage)
+	}
+	sort.Strings(files)
+	for _, fName := range files {
+		a, err := readArtifact(fName)
+		if err != nil {
+			return artifacts, err
+		}
+		artifacts = append(artifacts, a)
+	}
+	return artifacts, nil
+}
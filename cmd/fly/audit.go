@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/registry"
+)
+
+type status string
+
+const (
+	included status = "included"
+	excluded status = "excluded"
+	failed   status = "failed"
+)
+
+type auditEntry struct {
+	artist string
+	status status
+	reason registry.Reason
+	err    string
+}
+
+// Records what happened to every artist on the input list, so the omissions
+// described in the README can be reproduced.
+type audit struct {
+	entries []auditEntry
+	// Gigs flagged while merging event sources, for a human to check.
+	flagged [][]string
+}
+
+func (a *audit) include(artist string) {
+	a.entries = append(a.entries, auditEntry{artist: artist, status: included})
+}
+
+func (a *audit) exclude(artist string, reason registry.Reason) {
+	a.entries = append(a.entries, auditEntry{artist: artist, status: excluded, reason: reason})
+}
+
+func (a *audit) fail(artist string, err string) {
+	a.entries = append(a.entries, auditEntry{artist: artist, status: failed, err: err})
+}
+
+func (a *audit) review(artist string, events crawler.Events) {
+	for d, day := range events {
+		for _, e := range day {
+			for _, flag := range e.Flags {
+				a.flagged = append(a.flagged, []string{artist, d.Format("2006-01-02"), e.Title, e.Location, e.Source, flag})
+			}
+		}
+	}
+}
+
+var reviewHeaders = []string{"ARTIST", "DATE", "TITLE", "LOCATION", "SOURCE", "FLAG"}
+
+func (a *audit) writeReview(fName string) error {
+	sort.Slice(a.flagged, func(i, j int) bool {
+		if a.flagged[i][0] != a.flagged[j][0] {
+			return a.flagged[i][0] < a.flagged[j][0]
+		}
+		return a.flagged[i][1] < a.flagged[j][1]
+	})
+	return writeCSV(fName, append([][]string{reviewHeaders}, a.flagged...))
+}
+
+var auditHeaders = []string{"ARTIST", "STATUS", "REASON", "ERROR"}
+var auditSummaryHeaders = []string{"STATUS", "REASON", "ARTISTS"}
+
+// Write the per-artist audit to fName and the counts per status and reason
+// next to it, e.g. audit.csv and audit-summary.csv.
+func (a *audit) write(fName string) error {
+	sort.Slice(a.entries, func(i, j int) bool {
+		return a.entries[i].artist < a.entries[j].artist
+	})
+	rows := [][]string{auditHeaders}
+	counts := make(map[status]int)
+	reasons := make(map[registry.Reason]int)
+	for _, e := range a.entries {
+		rows = append(rows, []string{e.artist, string(e.status), string(e.reason), e.err})
+		counts[e.status]++
+		if e.status == excluded {
+			reasons[e.reason]++
+		}
+	}
+	if err := writeCSV(fName, rows); err != nil {
+		return err
+	}
+
+	summary := [][]string{auditSummaryHeaders}
+	summary = append(summary, []string{string(included), "", fmt.Sprintf("%d", counts[included])})
+	for _, reason := range registry.Reasons {
+		summary = append(summary, []string{string(excluded), string(reason), fmt.Sprintf("%d", reasons[reason])})
+	}
+	summary = append(summary, []string{string(failed), "", fmt.Sprintf("%d", counts[failed])})
+	summary = append(summary, []string{"total", "", fmt.Sprintf("%d", len(a.entries))})
+	ext := filepath.Ext(fName)
+	return writeCSV(strings.TrimSuffix(fName, ext)+"-summary"+ext, summary)
+}
+
+func writeCSV(fName string, rows [][]string) error {
+	csvfile, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer csvfile.Close()
+	csvwriter := csv.NewWriter(csvfile)
+	csvwriter.WriteAll(rows)
+	return csvwriter.Error()
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Backends fly can be pointed at, see config.
+const (
+	eventsRA    = "ra"
+	eventsFiles = "files"
+
+	geocoderGoogle = "google"
+	geocoderNone   = "none"
+
+	airportsEdge = "aviation-edge"
+
+	emissionsAtmosfair = "atmosfair"
+)
+
+/*
+config is the -config file, in yaml. It picks the backend for each upstream
+and holds their settings, so a run is described in one place:
+
+	tour_year: 2019
+	inputs:
+	  artists: ./artists.csv
+	  airports: ./airports.csv
+	events:
+	  source: ra
+	geocoder:
+	  backend: google
+	  api_key: ${GOOGLE_API_KEY}
+	airports:
+	  backend: aviation-edge
+	  api_key: ${EDGE_API_KEY}
+	emissions:
+	  backend: atmosfair
+	  account_id: ${ATMOS_ACCOUNT_ID}
+	  password: ${ATMOS_PASSWORD}
+
+${VAR} in a setting is replaced by the environment variable, so secrets can
+stay out of the file; any other $ is kept as written. Every setting has a flag of the same meaning, see bindings; the
+file overrides the flag defaults and flags given on the command line
+override the file.
+*/
+type config struct {
+	TourYear string `yaml:"tour_year,omitempty"`
+	Scenario string `yaml:"scenario,omitempty"`
+	WorkDir  string `yaml:"work_dir,omitempty"`
+	Workers  string `yaml:"workers,omitempty"`
+
+	Inputs struct {
+		Artists   string `yaml:"artists,omitempty"`
+		Airports  string `yaml:"airports,omitempty"`
+		Venues    string `yaml:"venues,omitempty"`
+		Routes    string `yaml:"routes,omitempty"`
+		EventsDir string `yaml:"events_dir,omitempty"`
+	} `yaml:"inputs"`
+
+	Outputs struct {
+		Dir      string `yaml:"dir,omitempty"`
+		Format   string `yaml:"format,omitempty"`
+		Store    string `yaml:"store,omitempty"`
+		ToursDir string `yaml:"tours_dir,omitempty"`
+		Review   string `yaml:"review,omitempty"`
+		Coverage string `yaml:"coverage,omitempty"`
+		Audit    string `yaml:"audit,omitempty"`
+	} `yaml:"outputs"`
+
+	Events struct {
+		Source      string `yaml:"source,omitempty"`
+		Concurrency string `yaml:"concurrency,omitempty"`
+		RPS         string `yaml:"rps,omitempty"`
+	} `yaml:"events"`
+
+	Geocoder struct {
+		Backend     string `yaml:"backend,omitempty"`
+		APIKey      string `yaml:"api_key,omitempty"`
+		Concurrency string `yaml:"concurrency,omitempty"`
+		RPS         string `yaml:"rps,omitempty"`
+	} `yaml:"geocoder"`
+
+	Airports struct {
+		Backend     string `yaml:"backend,omitempty"`
+		APIKey      string `yaml:"api_key,omitempty"`
+		Concurrency string `yaml:"concurrency,omitempty"`
+		RPS         string `yaml:"rps,omitempty"`
+	} `yaml:"airports"`
+
+	Emissions struct {
+		Backend     string `yaml:"backend,omitempty"`
+		AccountID   string `yaml:"account_id,omitempty"`
+		Password    string `yaml:"password,omitempty"`
+		Concurrency string `yaml:"concurrency,omitempty"`
+		RPS         string `yaml:"rps,omitempty"`
+	} `yaml:"emissions"`
+
+	Schedule struct {
+		ArrivalShort  string `yaml:"arrival_short,omitempty"`
+		ArrivalMedium string `yaml:"arrival_medium,omitempty"`
+		ArrivalLong   string `yaml:"arrival_long,omitempty"`
+	} `yaml:"schedule"`
+}
+
+// A config setting and the flag it stands for.
+type binding struct {
+	flag   string
+	value  *string
+	secret bool
+}
+
+func (c *config) bindings() []binding {
+	return []binding{
+		{"tour.year", &c.TourYear, false},
+		{"scenario", &c.Scenario, false},
+		{"work.dir", &c.WorkDir, false},
+		{"workers", &c.Workers, false},
+		{"artist.inputs", &c.Inputs.Artists, false},
+		{"airport.inputs", &c.Inputs.Airports, false},
+		{"venues.file", &c.Inputs.Venues, false},
+		{"routes.inputs", &c.Inputs.Routes, false},
+		{"events.dir", &c.Inputs.EventsDir, false},
+		{"output.dir", &c.Outputs.Dir, false},
+		{"output.format", &c.Outputs.Format, false},
+		{"store.file", &c.Outputs.Store, false},
+		{"tours.dir", &c.Outputs.ToursDir, false},
+		{"review.file", &c.Outputs.Review, false},
+		{"coverage.file", &c.Outputs.Coverage, false},
+		{"audit.file", &c.Outputs.Audit, false},
+		{"events.source", &c.Events.Source, false},
+		{"crawler.concurrency", &c.Events.Concurrency, false},
+		{"crawler.rps", &c.Events.RPS, false},
+		{"geocoder.backend", &c.Geocoder.Backend, false},
+		{"google.apikey", &c.Geocoder.APIKey, true},
+		{"geocoder.concurrency", &c.Geocoder.Concurrency, false},
+		{"geocoder.rps", &c.Geocoder.RPS, false},
+		{"airports.backend", &c.Airports.Backend, false},
+		{"edge.apiKey", &c.Airports.APIKey, true},
+		{"airports.concurrency", &c.Airports.Concurrency, false},
+		{"airports.rps", &c.Airports.RPS, false},
+		{"emissions.backend", &c.Emissions.Backend, false},
+		{"atmos.acctID", &c.Emissions.AccountID, true},
+		{"atmos.pass", &c.Emissions.Password, true},
+		{"atmos.concurrency", &c.Emissions.Concurrency, false},
+		{"atmos.rps", &c.Emissions.RPS, false},
+		{"arrival.short", &c.Schedule.ArrivalShort, false},
+		{"arrival.medium", &c.Schedule.ArrivalMedium, false},
+		{"arrival.long", &c.Schedule.ArrivalLong, false},
+	}
+}
+
+func loadConfig(fName string) (config, error) {
+	var c config
+	data, err := ioutil.ReadFile(fName)
+	if err != nil {
+		return c, err
+	}
+	if err := yaml.UnmarshalStrict(data, &c); err != nil {
+		return c, fmt.Errorf("%s: %v", fName, err)
+	}
+	for _, b := range c.bindings() {
+		*b.value = expandEnv(*b.value)
+	}
+	return c, nil
+}
+
+var envRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// Replaces only ${VAR}, a bare $ in a key or a path is left alone.
+func expandEnv(s string) string {
+	return envRef.ReplaceAllStringFunc(s, func(ref string) string {
+		return os.Getenv(envRef.FindStringSubmatch(ref)[1])
+	})
+}
+
+// Sets the flags from the config, leaving those given on the command line.
+func (c *config) apply() error {
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) { given[f.Name] = true })
+	for _, b := range c.bindings() {
+		if *b.value == "" || given[b.flag] {
+			continue
+		}
+		if err := flag.Set(b.flag, *b.value); err != nil {
+			return fmt.Errorf("%s: %v", b.flag, err)
+		}
+	}
+	return nil
+}
+
+// The config the flags add up to, with secrets redacted, for -print-config.
+func effectiveConfig() ([]byte, error) {
+	var c config
+	for _, b := range c.bindings() {
+		*b.value = flag.Lookup(b.flag).Value.String()
+		if b.secret && *b.value != "" {
+			*b.value = "<redacted>"
+		}
+	}
+	return yaml.Marshal(c)
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfigExpandsEnv(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	os.Setenv("FLY_TEST_ACCOUNT", "acct")
+	os.Setenv("HOME_DIR", "/home/nobody")
+	t.Cleanup(func() {
+		os.Unsetenv("FLY_TEST_ACCOUNT")
+		os.Unsetenv("HOME_DIR")
+	})
+	fName := filepath.Join(dir, "run.yaml")
+	data := "inputs:\n  artists: $HOME_DIR/artists.csv\n" +
+		"emissions:\n  account_id: ${FLY_TEST_ACCOUNT}\n  password: pa$$w0rd${UNSET_FLY_TEST}\n"
+	if err := ioutil.WriteFile(fName, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	c, err := loadConfig(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Emissions.AccountID != "acct" {
+		t.Errorf("account id %q, want it from the environment", c.Emissions.AccountID)
+	}
+	if c.Emissions.Password != "pa$$w0rd" {
+		t.Errorf("password %q, want its $ kept and the unset ${VAR} empty", c.Emissions.Password)
+	}
+	if c.Inputs.Artists != "$HOME_DIR/artists.csv" {
+		t.Errorf("artists %q, want a bare $VAR kept as written", c.Inputs.Artists)
+	}
+}
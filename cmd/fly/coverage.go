@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// How much of an artist's touring made it into the numbers.
+type coverage struct {
+	artist string
+	// Gigs listed across sources, then after merging duplicates.
+	scraped   int
+	events    int
+	geocoded  int
+	airported int
+	planned   int
+	emitted   int
+	err       string
+}
+
+// What was lost and why, one row per gig or flight.
+type coverageFailure struct {
+	artist string
+	date   string
+	what   string
+	cause  string
+}
+
+/*
+Event coverage is the share of gigs placed at an airport, leg coverage the
+share of planned flights with emissions. Their product is the share of the
+artist's touring counted, a flight lost to a gig without an airport being
+lost twice over. Without flights, only the gigs count.
+*/
+func (c coverage) percent() float64 {
+	if c.events == 0 {
+		return 0
+	}
+	share := float64(c.airported) / float64(c.events)
+	if c.planned > 0 {
+		share *= float64(c.emitted) / float64(c.planned)
+	}
+	return 100 * share
+}
+
+type coverageReport struct {
+	artists  []coverage
+	failures []coverageFailure
+}
+
+func (r *coverageReport) add(a artifact) {
+	c := coverage{artist: a.Artist.Name, scraped: a.Scraped, events: a.Events.Count(), err: a.Error}
+	if a.Error != "" {
+		r.failures = append(r.failures, coverageFailure{a.Artist.Name, "", "artist", a.Error})
+	}
+	for d, day := range a.Events {
+		for _, e := range day {
+			if e.Lat != 0 || e.Lng != 0 {
+				c.geocoded++
+			}
+			if e.AirCode != "" {
+				c.airported++
+				continue
+			}
+			cause := e.Failure
+			if cause == "" {
+				cause = "no airport found"
+			}
+			r.failures = append(r.failures, coverageFailure{a.Artist.Name, d.Format("2006-01-02"), "gig " + e.Title, cause})
+		}
+	}
+
+	// Calculate returns an output per trip that had both airports, in order,
+	// matched to them by route and date
+	c.planned = len(a.Trips)
+	next := 0
+	for _, trip := range a.Trips {
+		leg := fmt.Sprintf("flight %s-%s", trip.DepCode, trip.ArrCode)
+		date := trip.Date.Format("2006-01-02")
+		if trip.DepCode == "" || trip.ArrCode == "" {
+			r.failures = append(r.failures, coverageFailure{a.Artist.Name, date, leg, "gig without an airport"})
+			continue
+		}
+		if next >= len(a.Outputs) {
+			if a.Error == "" {
+				r.failures = append(r.failures, coverageFailure{a.Artist.Name, date, leg, "no emissions calculated"})
+			}
+			continue
+		}
+		if a.Outputs[next].CarbonOutput > 0 {
+			c.emitted++
+		} else {
+			r.failures = append(r.failures, coverageFailure{a.Artist.Name, date, leg, "atmosfair returned no emissions"})
+		}
+		next++
+	}
+	r.artists = append(r.artists, c)
+}
+
+var coverageHeaders = []string{"ARTIST", "EVENTS SCRAPED", "EVENTS", "GEOCODED", "WITH AIRPORT", "LEGS PLANNED", "LEGS WITH EMISSIONS", "COVERAGE", "ERROR"}
+var coverageFailureHeaders = []string{"ARTIST", "DATE", "WHAT", "CAUSE"}
+
+// Write the per-artist coverage, totalled last, to fName and every failure
+// next to it, e.g. coverage.csv and coverage-failures.csv.
+func (r *coverageReport) write(fName string) error {
+	sort.Slice(r.artists, func(i, j int) bool {
+		return r.artists[i].artist < r.artists[j].artist
+	})
+	row := func(c coverage) []string {
+		return []string{
+			c.artist,
+			fmt.Sprintf("%d", c.scraped),
+			fmt.Sprintf("%d", c.events),
+			fmt.Sprintf("%d", c.geocoded),
+			fmt.Sprintf("%d", c.airported),
+			fmt.Sprintf("%d", c.planned),
+			fmt.Sprintf("%d", c.emitted),
+			fmt.Sprintf("%.1f%%", c.percent()),
+			c.err,
+		}
+	}
+	rows := [][]string{coverageHeaders}
+	total := coverage{artist: "total"}
+	for _, c := range r.artists {
+		rows = append(rows, row(c))
+		total.scraped += c.scraped
+		total.events += c.events
+		total.geocoded += c.geocoded
+		total.airported += c.airported
+		total.planned += c.planned
+		total.emitted += c.emitted
+	}
+	rows = append(rows, row(total))
+	if err := writeCSV(fName, rows); err != nil {
+		return err
+	}
+	fmt.Printf("Coverage: %.1f%% of %d gigs and %d flights\n", total.percent(), total.events, total.planned)
+
+	sort.SliceStable(r.failures, func(i, j int) bool {
+		if r.failures[i].artist != r.failures[j].artist {
+			return r.failures[i].artist < r.failures[j].artist
+		}
+		return r.failures[i].date < r.failures[j].date
+	})
+	failures := [][]string{coverageFailureHeaders}
+	for _, f := range r.failures {
+		failures = append(failures, []string{f.artist, f.date, f.what, f.cause})
+	}
+	ext := filepath.Ext(fName)
+	return writeCSV(strings.TrimSuffix(fName, ext)+"-failures"+ext, failures)
+}
@@ -1,31 +1,59 @@
 package main
 
 import (
-	"encoding/csv"
+	"context"
 	"flag"
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 
-	"github.com/cleanscene.flights/lib/airports"
-	"github.com/cleanscene.flights/lib/atmos"
-	"github.com/cleanscene.flights/lib/crawler"
 	"github.com/cleanscene.flights/lib/flight"
-	"github.com/cleanscene.flights/lib/google"
-	"github.com/cleanscene.flights/lib/ra"
-	country_mapper "github.com/pirsquare/country-mapper"
+	"github.com/cleanscene.flights/lib/throttle"
 )
 
 var (
-	tourYear    = flag.String("tour.year", "2019", "year in which to scrape artist event schedule")
-	outputDir   = flag.String("output.dir", "./done/artist-pages", "directory to write flight data csv output to")
-	artistFile  = flag.String("artist.inputs", os.Getenv("ARTISTS_INPUT"), "precompiled, editied list of the RA artists")
-	airportFile = flag.String("airport.inputs", os.Getenv("AIRPORT_INPUT"), "precompiled list of major airpot codes and their major city")
+	configFile   = flag.String("config", "", "optional yaml file of backends and settings, see config; flags given override it")
+	printConfig  = flag.Bool("print-config", false, "print the settings in effect, secrets redacted, and exit")
+	workDir      = flag.String("work.dir", "./done/work", "directory to keep each stage's per-artist json artifacts in")
+	retryFailed  = flag.Bool("retry-failed", false, "run artists a stage failed for again, instead of skipping them on resume")
+	tourYear     = flag.String("tour.year", "2019", "year in which to scrape artist event schedule")
+	outputDir    = flag.String("output.dir", "./done/artist-pages", "directory to write flight data csv output to")
+	outputFormat = flag.String("output.format", "csv", "format of the per-artist flight files, csv or jsonl, see output.SchemaVersion")
+	storeFile    = flag.String("store.file", "", "optional sqlite file to also write artists, events, venues, trips and emissions to, see store.Store")
+	scenario     = flag.String("scenario", "", "name the run's rows are kept under in the store, the tour year by default")
+	toursDir     = flag.String("tours.dir", "./done/tours", "directory to write per-tour emissions csv output to")
+	reviewFile   = flag.String("review.file", "./done/review.csv", "file to list gigs needing review, such as impossible schedules")
+	coverageFile = flag.String("coverage.file", "./done/coverage.csv", "file to report how many gigs and flights of each artist were counted, and why the rest were not")
+	auditFile    = flag.String("audit.file", "./done/audit.csv", "file to record which artists were included, excluded or failed")
+	artistFile   = flag.String("artist.inputs", os.Getenv("ARTISTS_INPUT"), "precompiled, editied list of the RA artists")
+	airportFile  = flag.String("airport.inputs", os.Getenv("AIRPORT_INPUT"), "precompiled list of major airpot codes and their major city")
+	venuesFile   = flag.String("venues.file", "./venues.json", "venue registry caching club addresses, coordinates and airports between runs")
+	eventsDir    = flag.String("events.dir", "", "optional directory of per-artist gig lists (csv, json or ics), used by registry entries listing the \"files\" source")
+	routesFile   = flag.String("routes.inputs", os.Getenv("ROUTES_INPUT"), "optional openflights routes.dat, used to infer connections where there is no direct flight")
+
+	eventsSource     = flag.String("events.source", eventsRA, "where gigs of artists without sources of their own come from, ra or files (from events.dir)")
+	geocoderBackend  = flag.String("geocoder.backend", geocoderGoogle, "geocoder for venues without coordinates, google or none")
+	airportsBackend  = flag.String("airports.backend", airportsEdge, "service finding the nearest airport to a venue, aviation-edge")
+	emissionsBackend = flag.String("emissions.backend", emissionsAtmosfair, "service working out flight emissions, atmosfair")
 
 	googleApiKey  = flag.String("google.apikey", os.Getenv("GOOGLE_API_KEY"), "google api key for airports svc")
 	atmosAcctID   = flag.String("atmos.acctID", os.Getenv("ATMOS_ACCOUNT_ID"), "account id for atmosfaire api")
 	atmosPassword = flag.String("atmos.pass", os.Getenv("ATMOS_PASSWORD"), "password for atmosfaire api")
 	edgeApiKey    = flag.String("edge.apiKey", os.Getenv("EDGE_API_KEY"), "key for edge api to find nearst airport code")
+
+	arrivalShort  = flag.Duration("arrival.short", flight.DefaultSchedule().ArrivalBuffer[flight.ShortHaul], "how long before a short-haul gig the artist leaves")
+	arrivalMedium = flag.Duration("arrival.medium", flight.DefaultSchedule().ArrivalBuffer[flight.MediumHaul], "how long before a medium-haul gig the artist leaves")
+	arrivalLong   = flag.Duration("arrival.long", flight.DefaultSchedule().ArrivalBuffer[flight.LongHaul], "how long before a long-haul gig the artist leaves")
+	workers       = flag.Int("workers", 4, "number of artists to work on at once")
+	crawlerConc   = flag.Int("crawler.concurrency", 2, "requests to RA in flight at once")
+	crawlerRate   = flag.Float64("crawler.rps", 1, "requests a second to RA")
+	geocoderConc  = flag.Int("geocoder.concurrency", 4, "requests to google places in flight at once")
+	geocoderRate  = flag.Float64("geocoder.rps", 10, "requests a second to google places")
+	airportsConc  = flag.Int("airports.concurrency", 4, "requests to aviation-edge in flight at once")
+	airportsRate  = flag.Float64("airports.rps", 5, "requests a second to aviation-edge")
+	atmosConc     = flag.Int("atmos.concurrency", 2, "requests to atmosfair in flight at once")
+	atmosRate     = flag.Float64("atmos.rps", 2, "requests a second to atmosfair")
 )
 
 // We begin by crawling the RA top 1000 artists.
@@ -34,13 +62,10 @@ const baseUrl = "https://www.residentadvisor.net"
 // Atmosfair api url use for flight emission calculations.
 const atmosUrl = "https://api.atmosfair.de/api/emission/flight"
 
-// For every event for each artist, we record these data points.
-var metaDataHeaders = []string{"DEPARTURE", "ARRIVAL", "DATE", "OFFSET", "CARBON OUTPUT", "FUEL", "DISTANCE"}
-
 // By default, dont fail on error simply log.
 var errCheck = func(err error) {
 	if err != nil {
-		log.Info(err)
+		log.Println(err)
 	}
 }
 
@@ -51,88 +76,156 @@ var errFail = func(err error) {
 	}
 }
 
-// Write flight & carbon output data to artist csv file
-func writeTo(outputs []atmos.Output, artistName, outputDir string) {
-	csvfile, err := os.Create(fmt.Sprintf("%s/%s.csv", outputDir, artistName))
-	errFail(err)
-	csvwriter := csv.NewWriter(csvfile)
-	csvwriter.Write(metaDataHeaders)
-	for _, output := range outputs {
-		row := []string{
-			output.DepartCode,
-			output.ArrivalCode,
-			output.FlightDay,
-			fmt.Sprintf("€%f", output.OffsetEuros),
-			fmt.Sprintf("%f kg", output.CarbonOutput),
-			fmt.Sprintf("%f L", output.FuelInLiter),
-			fmt.Sprintf("%d km", output.Distance),
-		}
-		err = csvwriter.Write(row)
-		errCheck(err)
-	}
-
-	csvwriter.Flush()
-	csvfile.Close()
-}
-
-func main() {
-	parseFlags()
-
-	djCrawler, err := crawler.New(baseUrl)
-	errFail(err)
-
-	googleApi := google.NewApi(*googleApiKey)
-	airSvc, err := airports.New(*airportFile, *edgeApiKey, googleApi)
-	errFail(err)
-
-	raSvc := ra.New(airSvc, djCrawler, *outputDir, *tourYear)
-	cclient, err := country_mapper.Load()
-	errFail(err)
+/*
+fly runs the study in stages, each writing a json artifact per artist under
+work.dir for the next to read, so a stage can be rerun or inspected without
+redoing the ones before it:
 
-	planner := flight.NewPlanner(cclient)
-	atmosSvc := atmos.NewFair(atmosUrl, *atmosAcctID, *atmosPass)
+	fly [scrape|resolve|plan|emit|report|all]
 
-	artists, err := raSvc.LoadArtists(*artistFile)
+Without a stage, all of them run in order. Progress is kept in
+work.dir/progress.json, a rerun skips the artists each stage is done for.
+*/
+func main() {
+	stage := parseFlags()
+	errFail(os.MkdirAll(*workDir, 0755))
+	var err error
+	runProgress, err = loadProgress(filepath.Join(*workDir, "progress.json"))
 	errFail(err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	handleInterrupt(cancel)
+	clients = upstreams{
+		crawler:  throttle.New(*crawlerConc, *crawlerRate),
+		geocoder: throttle.New(*geocoderConc, *geocoderRate),
+		airports: throttle.New(*airportsConc, *airportsRate),
+		atmos:    throttle.New(*atmosConc, *atmosRate),
+	}
 
-	for _, artist := range artists {
-		events, err := raSvc.LoadEvents(artist)
-		errCheck(err)
-		artist.Events = events
-		trips, err := planner.Plan(artist)
-		errCheck(err)
-		outputs, err := atmosSvc.Calculate(trips)
-		errCheck(err)
-		writeTo(outputs, artist.Name, *outputDir)
+	run := stages
+	if stage != "all" {
+		run = []string{stage}
+	}
+	for _, stage := range run {
+		fmt.Printf("Running %s..\n", stage)
+		err := runStage[stage](ctx)
+		if err == errInterrupted {
+			fmt.Printf("Stopped during %s, progress saved, rerun to resume.\n", stage)
+			os.Exit(130)
+		}
+		errFail(err)
 	}
+}
+
+var runProgress *progress
 
+// Each upstream has its own limits, shared by all the workers.
+type upstreams struct {
+	crawler  throttle.Client
+	geocoder throttle.Client
+	airports throttle.Client
+	atmos    throttle.Client
 }
 
-func parseFlags() {
+var clients upstreams
+
+// Checks the flags the stage needs, credentials only for the backends it uses.
+func parseFlags() string {
 	flag.Parse()
-	if *tourYear == "" {
-		log.Fatal("missing tour year for aritst")
+	if *configFile != "" {
+		c, err := loadConfig(*configFile)
+		errFail(err)
+		errFail(c.apply())
 	}
-	if *outputDir == "" {
-		log.Fatal("outputdir missing to write files to")
+	if *printConfig {
+		data, err := effectiveConfig()
+		errFail(err)
+		fmt.Print(string(data))
+		os.Exit(0)
 	}
-	if *artistFile == "" {
-		log.Fatal("missing pre-compiled list of artists intended to scrape")
+	stage := "all"
+	if flag.NArg() > 0 {
+		stage = flag.Arg(0)
 	}
-	if *airportFile == "" {
-		log.Fatal("missing pre-compiled list of artists intended to scrape")
+	if _, ok := runStage[stage]; !ok && stage != "all" {
+		log.Fatalf("unknown stage %q, expected one of %v or all", stage, stages)
 	}
-	if *googleApiKey == "" {
-		log.Fatal("missing googlepai key to find nearest airport")
+	runs := func(s string) bool { return stage == s || stage == "all" }
+	if *workers < 1 {
+		log.Fatal("need at least one worker")
 	}
-	if *atmosAcctID == "" {
-		log.Fatal("atmosfaire account id for carbon emissions api")
+	if *workDir == "" {
+		log.Fatal("work dir missing to keep stage artifacts in")
 	}
-	if *atmosPass == "" {
-		log.Fatal("atmosfaire password for carbon emissions api")
+	if runs("scrape") || runs("resolve") {
+		if *tourYear == "" {
+			log.Fatal("missing tour year for aritst")
+		}
+		if *airportFile == "" {
+			log.Fatal("missing pre-compiled list of airports")
+		}
 	}
-	if *edgeApiKey == "" {
-		log.Fatal("edge api key missing for nearest aircode")
+	if runs("scrape") {
+		if *artistFile == "" {
+			log.Fatal("missing pre-compiled list of artists intended to scrape")
+		}
+		switch *eventsSource {
+		case eventsRA:
+		case eventsFiles:
+			if *eventsDir == "" {
+				log.Fatal("events dir missing to read gig lists from")
+			}
+		default:
+			log.Fatalf("unknown event source %q, expected ra or files", *eventsSource)
+		}
 	}
-
+	if runs("resolve") {
+		switch *geocoderBackend {
+		case geocoderGoogle:
+			if *googleApiKey == "" {
+				log.Fatal("missing googlepai key to find nearest airport")
+			}
+		case geocoderNone:
+		default:
+			log.Fatalf("unknown geocoder %q, expected google or none", *geocoderBackend)
+		}
+		if *airportsBackend != airportsEdge {
+			log.Fatalf("unknown airports backend %q, expected aviation-edge", *airportsBackend)
+		}
+		if *edgeApiKey == "" {
+			log.Fatal("edge api key missing for nearest aircode")
+		}
+	}
+	if runs("emit") {
+		if *emissionsBackend != emissionsAtmosfair {
+			log.Fatalf("unknown emissions backend %q, expected atmosfair", *emissionsBackend)
+		}
+		if *atmosAcctID == "" {
+			log.Fatal("atmosfaire account id for carbon emissions api")
+		}
+		if *atmosPassword == "" {
+			log.Fatal("atmosfaire password for carbon emissions api")
+		}
+	}
+	if runs("report") {
+		if *outputDir == "" {
+			log.Fatal("outputdir missing to write files to")
+		}
+		if *outputFormat != "csv" && *outputFormat != "jsonl" {
+			log.Fatalf("unknown output format %q, expected csv or jsonl", *outputFormat)
+		}
+		if *toursDir == "" {
+			log.Fatal("toursdir missing to write tour files to")
+		}
+		if *auditFile == "" {
+			log.Fatal("audit file missing to record artist omissions")
+		}
+		if *reviewFile == "" {
+			log.Fatal("review file missing to list flagged gigs")
+		}
+		if *coverageFile == "" {
+			log.Fatal("coverage file missing to report what was counted")
+		}
+	}
+	return stage
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+type state string
+
+const (
+	stateDone   state = "done"
+	stateFailed state = "failed"
+)
+
+type stageRecord struct {
+	State state     `json:"state"`
+	Error string    `json:"error,omitempty"`
+	At    time.Time `json:"at"`
+}
+
+/*
+progress records which stages each artist has been through, so a run that
+stopped part way picks up where it left off. An artist is only recorded
+once their artifact is written, and redoing a stage for them clears the
+stages after it, which then run again.
+*/
+type progress struct {
+	mu      sync.Mutex
+	fName   string
+	Artists map[string]map[string]stageRecord `json:"artists"`
+}
+
+func loadProgress(fName string) (*progress, error) {
+	p := &progress{fName: fName, Artists: make(map[string]map[string]stageRecord)}
+	data, err := ioutil.ReadFile(fName)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return p, err
+	}
+	if err := json.Unmarshal(data, p); err != nil {
+		return p, fmt.Errorf("%s: %v", fName, err)
+	}
+	return p, nil
+}
+
+// Pending is true unless the stage is done for the artist, or failed and
+// failures are not being retried.
+func (p *progress) pending(artist, stage string, retryFailed bool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rec, ok := p.Artists[artist][stage]
+	if !ok {
+		return true
+	}
+	if _, err := os.Stat(artifactPath(stage, artist)); err != nil {
+		return true
+	}
+	return rec.State == stateFailed && retryFailed
+}
+
+func (p *progress) record(stage string, a artifact) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	rec := stageRecord{State: stateDone, At: time.Now().UTC()}
+	if a.Error != "" {
+		rec.State, rec.Error = stateFailed, a.Error
+	}
+	recs := p.Artists[a.Artist.Name]
+	if recs == nil {
+		recs = make(map[string]stageRecord)
+		p.Artists[a.Artist.Name] = recs
+	}
+	later := false
+	for _, s := range stages {
+		if later {
+			delete(recs, s)
+		}
+		later = later || s == stage
+	}
+	recs[stage] = rec
+	return p.save()
+}
+
+// Written to a temporary file first, so an interrupted save leaves the last
+// good copy in place.
+func (p *progress) save() error {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := p.fName + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, p.fName)
+}
+
+var errInterrupted = errors.New("interrupted")
+
+var interrupted int32
+
+// On the first interrupt the artists in hand are finished and their progress
+// saved before stopping. A second one cancels the requests in flight, those
+// artists run again on resume, and a third quits straight away.
+func handleInterrupt(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 3)
+	signal.Notify(signals, os.Interrupt)
+	go func() {
+		<-signals
+		atomic.StoreInt32(&interrupted, 1)
+		fmt.Println("Stopping after the current artists, interrupt again to cancel them..")
+		<-signals
+		cancel()
+		<-signals
+		os.Exit(1)
+	}()
+}
+
+func stopping() bool {
+	return atomic.LoadInt32(&interrupted) == 1
+}
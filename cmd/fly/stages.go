@@ -0,0 +1,373 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/cleanscene.flights/lib/airports"
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/google"
+	"github.com/cleanscene.flights/lib/output"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/region"
+	"github.com/cleanscene.flights/lib/source"
+	"github.com/cleanscene.flights/lib/store"
+	"github.com/cleanscene.flights/lib/venue"
+)
+
+// Stages in the order they run, each reading what the one before it wrote.
+var stages = []string{"scrape", "resolve", "plan", "emit", "report"}
+
+var runStage = map[string]func(context.Context) error{
+	"scrape":  scrape,
+	"resolve": resolve,
+	"plan":    plan,
+	"emit":    emit,
+	"report":  report,
+}
+
+// Runs a stage over every artifact of the one before it, skipping artists
+// it is already done for.
+func step(ctx context.Context, from, to string, run func(context.Context, *artifact)) error {
+	artifacts, err := readArtifacts(from)
+	if err != nil {
+		return err
+	}
+	var (
+		names   []string
+		byName  = make(map[string]artifact)
+		pending []string
+	)
+	for _, a := range artifacts {
+		names = append(names, a.Artist.Name)
+		byName[a.Artist.Name] = a
+		if runProgress.pending(a.Artist.Name, to, *retryFailed) {
+			pending = append(pending, a.Artist.Name)
+		}
+	}
+	err = pool(ctx, pending, func(name string) error {
+		a := byName[name]
+		if !a.skip() {
+			run(ctx, &a)
+		}
+		return finish(ctx, to, a)
+	})
+	if err != nil {
+		return err
+	}
+	return prune(to, names)
+}
+
+/*
+pool works through the artists on up to -workers goroutines. On interrupt no
+more artists are started, those in hand are finished and recorded, and
+errInterrupted is returned. The first error stops the pool the same way.
+*/
+func pool(ctx context.Context, names []string, work func(string) error) error {
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+		jobs     = make(chan string)
+	)
+	failed := func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return firstErr != nil
+	}
+	for i := 0; i < *workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range jobs {
+				if err := work(name); err != nil {
+					mu.Lock()
+					if firstErr == nil {
+						firstErr = err
+					}
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, name := range names {
+		if stopping() || failed() || ctx.Err() != nil {
+			break
+		}
+		jobs <- name
+	}
+	close(jobs)
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if stopping() || ctx.Err() != nil {
+		return errInterrupted
+	}
+	return nil
+}
+
+// Records the artist as done for the stage, unless it was cut short by a
+// cancelled context, leaving them to run again on resume.
+func finish(ctx context.Context, stage string, a artifact) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	if err := writeArtifact(stage, a); err != nil {
+		return err
+	}
+	return runProgress.record(stage, a)
+}
+
+// Lists every artist's gigs from their event sources, as the sources give them.
+func scrape(ctx context.Context) error {
+	venues, err := venue.Load(*venuesFile)
+	if err != nil {
+		return err
+	}
+	// Only the local airport list is used, to find home airports
+	airSvc, err := airports.New(*airportFile, *edgeApiKey, geocoder(), clients.airports)
+	if err != nil {
+		return err
+	}
+	var (
+		djCrawler crawler.Crawler
+		sources   = source.Set{}
+	)
+	if *eventsSource == eventsRA {
+		if djCrawler, err = crawler.New(ctx, baseUrl, venues, clients.crawler); err != nil {
+			return err
+		}
+		sources[source.Default] = source.FromCrawler(djCrawler)
+	}
+	if *eventsDir != "" {
+		sources[eventsFiles] = source.NewDir(*eventsDir)
+	}
+	raSvc := ra.New(airSvc, djCrawler, sources, venues, *outputDir, *tourYear)
+	artists, err := raSvc.LoadArtists(*artistFile)
+	if err != nil {
+		return err
+	}
+	if *eventsSource == eventsFiles {
+		for name, artist := range artists {
+			if len(artist.Sources) == 0 {
+				artist.Sources = []string{eventsFiles}
+				artists[name] = artist
+			}
+		}
+	}
+	var names []string
+	for name := range artists {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var pending []string
+	for _, name := range names {
+		if runProgress.pending(name, "scrape", *retryFailed) {
+			pending = append(pending, name)
+		}
+	}
+	// The venues found so far are kept, even when interrupted
+	defer venues.Save()
+	err = pool(ctx, pending, func(name string) error {
+		a := artifact{Artist: artists[name]}
+		if !a.skip() {
+			listings, err := raSvc.ScrapeEvents(ctx, a.Artist)
+			if err != nil {
+				a.fail(err)
+			}
+			a.Listings = listings
+		}
+		return finish(ctx, "scrape", a)
+	})
+	if err != nil {
+		return err
+	}
+	if err := prune("scrape", names); err != nil {
+		return err
+	}
+	return venues.Save()
+}
+
+// The geocoder backend, none leaving venues without coordinates unplaced.
+func geocoder() google.Places {
+	if *geocoderBackend == geocoderNone {
+		return nil
+	}
+	return google.NewApi(*googleApiKey, clients.geocoder)
+}
+
+// Finds the airport of every gig and merges the sources' listings.
+func resolve(ctx context.Context) error {
+	venues, err := venue.Load(*venuesFile)
+	if err != nil {
+		return err
+	}
+	airSvc, err := airports.New(*airportFile, *edgeApiKey, geocoder(), clients.airports)
+	if err != nil {
+		return err
+	}
+	raSvc := ra.New(airSvc, nil, nil, venues, *outputDir, *tourYear)
+	defer venues.Save()
+	err = step(ctx, "scrape", "resolve", func(ctx context.Context, a *artifact) {
+		for _, listing := range a.Listings {
+			a.Scraped += listing.Events.Count()
+		}
+		a.Events = raSvc.ResolveEvents(ctx, a.Listings)
+		a.Listings = nil
+	})
+	if err != nil {
+		return err
+	}
+	return venues.Save()
+}
+
+// Plans the flights between gigs and groups them into tours.
+func plan(ctx context.Context) error {
+	schedule := flight.DefaultSchedule()
+	schedule.ArrivalBuffer[flight.ShortHaul] = *arrivalShort
+	schedule.ArrivalBuffer[flight.MediumHaul] = *arrivalMedium
+	schedule.ArrivalBuffer[flight.LongHaul] = *arrivalLong
+	planner := flight.NewPlanner(region.New(), schedule)
+	// Kept for the report's methodology page
+	if err := writeSchedule(schedule); err != nil {
+		return err
+	}
+
+	var routes flight.Routes
+	if *routesFile != "" {
+		var err error
+		if routes, err = flight.LoadRoutes(*routesFile); err != nil {
+			return err
+		}
+	}
+	return step(ctx, "resolve", "plan", func(_ context.Context, a *artifact) {
+		artist := a.Artist
+		artist.Events = ra.Events(a.Events)
+		trips, err := planner.Plan(artist)
+		if err != nil {
+			a.fail(err)
+			return
+		}
+		if routes != nil {
+			trips = routes.Connect(trips)
+		}
+		a.Trips = trips
+		a.Tours = planner.Tours(artist)
+	})
+}
+
+// Works out the emissions of every flight.
+func emit(ctx context.Context) error {
+	atmosSvc := atmos.NewFair(atmosUrl, *atmosAcctID, *atmosPassword, clients.atmos)
+	return step(ctx, "plan", "emit", func(ctx context.Context, a *artifact) {
+		outputs, err := atmosSvc.Calculate(ctx, a.Trips)
+		if err != nil {
+			a.fail(err)
+			return
+		}
+		a.Outputs = outputs
+	})
+}
+
+// Writes the per-artist and per-tour csvs, the audit, coverage and the gigs
+// to review.
+func report(context.Context) error {
+	artifacts, err := readArtifacts("emit")
+	if err != nil {
+		return err
+	}
+	for _, dir := range []string{*outputDir, *toursDir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	var (
+		runAudit audit
+		cov      coverageReport
+	)
+	for _, a := range artifacts {
+		if a.Artist.Excluded == "" {
+			cov.add(a)
+		}
+		switch {
+		case a.Artist.Excluded != "":
+			runAudit.exclude(a.Artist.Name, a.Artist.Excluded)
+			continue
+		case a.Error != "":
+			runAudit.fail(a.Artist.Name, a.Error)
+			continue
+		}
+		// One artist's csv failing to write should not lose the rest
+		if err := output.WriteFlights(a.Artist.Name, a.Outputs, fmt.Sprintf("%s/%s.%s", *outputDir, a.Artist.Name, *outputFormat)); err != nil {
+			errCheck(err)
+			runAudit.fail(a.Artist.Name, err.Error())
+			continue
+		}
+		if len(a.Tours) > 0 {
+			if err := output.WriteTours(a.Outputs, a.Tours, fmt.Sprintf("%s/%s.csv", *toursDir, a.Artist.Name)); err != nil {
+				errCheck(err)
+				runAudit.fail(a.Artist.Name, err.Error())
+				continue
+			}
+		}
+		runAudit.review(a.Artist.Name, a.Events)
+		runAudit.include(a.Artist.Name)
+	}
+	if *storeFile != "" {
+		if err := storeAll(artifacts); err != nil {
+			return err
+		}
+	}
+	if err := runAudit.write(*auditFile); err != nil {
+		return err
+	}
+	if err := cov.write(*coverageFile); err != nil {
+		return err
+	}
+	return runAudit.writeReview(*reviewFile)
+}
+
+// Writes the venues and every artist's results to the store, under the
+// scenario or the tour year.
+func storeAll(artifacts []artifact) error {
+	db, err := store.Open(*storeFile)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	venues, err := venue.Load(*venuesFile)
+	if err != nil {
+		return err
+	}
+	if err := db.PutVenues(venues.All()); err != nil {
+		return err
+	}
+	name := *scenario
+	if name == "" {
+		name = *tourYear
+	}
+	for _, a := range artifacts {
+		if err := db.PutArtist(name, a.Artist, a.Events, a.Trips, a.Outputs); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Stored %d artists as scenario %s in %s\n", len(artifacts), name, *storeFile)
+	return nil
+}
+
+func writeSchedule(schedule flight.Schedule) error {
+	data, err := json.MarshalIndent(schedule, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(*workDir, "schedule.json"), data, 0644)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/cleanscene.flights/lib/hometown"
+	"github.com/cleanscene.flights/lib/registry"
+	"github.com/cleanscene.flights/lib/throttle"
+)
+
+// Fills in the artist registry's hometowns the way the study did, first from
+// SoundCloud, then from RA, recording which one each came from.
+var (
+	artistFile = flag.String("artist.inputs", os.Getenv("ARTISTS_INPUT"), "artist registry to resolve hometowns for")
+	outputFile = flag.String("output", "", "file to write the updated registry to, the input file if empty")
+	refresh    = flag.Bool("refresh", false, "look up hometowns already taken from soundcloud or ra again")
+	exclude    = flag.Bool("exclude.missing", false, "exclude artists whose hometown could not be found, as the study did")
+	rate       = flag.Float64("rps", 1, "requests a second to soundcloud and to ra")
+)
+
+const baseUrl = "https://www.residentadvisor.net"
+
+func main() {
+	flag.Parse()
+	if *artistFile == "" {
+		log.Fatal("missing artist registry")
+	}
+	if *outputFile == "" {
+		*outputFile = *artistFile
+	}
+
+	entries, err := registry.LoadUnresolved(*artistFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ctx := context.Background()
+	finder := hometown.New(baseUrl, throttle.New(2, *rate))
+	var found, missing int
+	for i, entry := range entries {
+		if entry.Excluded != "" || entry.HometownSource == registry.Manual {
+			continue
+		}
+		resolved := entry.HometownSource == registry.SoundCloud || entry.HometownSource == registry.RA
+		if entry.City != "" && !(*refresh && resolved) {
+			continue
+		}
+		loc, src, err := finder.Find(ctx, entry)
+		if err != nil {
+			fmt.Printf("%s: %v\n", entry.Name, err)
+			missing++
+			if *exclude {
+				entries[i].Excluded = registry.NoHometown
+			}
+			continue
+		}
+		entries[i].City = loc.City
+		entries[i].Country = loc.Country
+		entries[i].HometownSource = src
+		found++
+	}
+	if err := registry.Save(*outputFile, entries); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d hometowns found, %d missing, written to %s\n", found, missing, *outputFile)
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cleanscene.flights/lib/airports"
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/google"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/region"
+	"github.com/cleanscene.flights/lib/registry"
+)
+
+// Estimates the flights of a prospective lineup before it is booked, each
+// artist flying in for the gig and home the morning after.
+var (
+	venueAddr    = flag.String("venue", "", "address or name of the venue, as you would search for it on a map")
+	gigDate      = flag.String("date", "", "date of the gig, e.g. 2019-06-14")
+	lineup       = flag.String("artists", "", "semicolon separated lineup, registry names or home cities as \"City, Country\"")
+	alternatives = flag.Int("alternatives", 5, "number of regional alternatives from the registry to estimate")
+	outputFile   = flag.String("output", "", "optional csv file to write the estimate to")
+	artistFile   = flag.String("artist.inputs", os.Getenv("ARTISTS_INPUT"), "artist registry to look up names and regional alternatives in")
+	airportFile  = flag.String("airport.inputs", os.Getenv("AIRPORT_INPUT"), "precompiled list of major airpot codes and their major city")
+	routesFile   = flag.String("routes.inputs", os.Getenv("ROUTES_INPUT"), "optional openflights routes.dat, used to infer connections where there is no direct flight")
+
+	googleApiKey  = flag.String("google.apikey", os.Getenv("GOOGLE_API_KEY"), "google api key for airports svc")
+	atmosAcctID   = flag.String("atmos.acctID", os.Getenv("ATMOS_ACCOUNT_ID"), "account id for atmosfaire api")
+	atmosPassword = flag.String("atmos.pass", os.Getenv("ATMOS_PASSWORD"), "password for atmosfaire api")
+	edgeApiKey    = flag.String("edge.apiKey", os.Getenv("EDGE_API_KEY"), "key for edge api to find nearst airport code")
+)
+
+// Atmosfair api url use for flight emission calculations.
+const atmosUrl = "https://api.atmosfair.de/api/emission/flight"
+
+// Bookings over this share of the lineup's total are called out.
+const dominantShare = 0.5
+
+var errFail = func(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+type booking struct {
+	artist  ra.Artist
+	flights int
+	carbon  float64
+	share   float64
+	// Set on regional alternatives.
+	alternative bool
+}
+
+type estimator struct {
+	airSvc   airports.Airports
+	planner  flight.Planner
+	routes   flight.Routes
+	atmosSvc atmos.AtmosFair
+	date     time.Time
+	gig      event.Event
+}
+
+func main() {
+	parseFlags()
+	date, err := time.Parse("2006-01-02", *gigDate)
+	errFail(err)
+
+	googleApi := google.NewApi(*googleApiKey, nil)
+	airSvc, err := airports.New(*airportFile, *edgeApiKey, googleApi, nil)
+	errFail(err)
+	edge, err := airSvc.FindClosestAirport(context.Background(), *venueAddr)
+	errFail(err)
+
+	est := estimator{
+		airSvc:   airSvc,
+		planner:  flight.NewPlanner(region.New(), flight.DefaultSchedule()),
+		atmosSvc: atmos.NewFair(atmosUrl, *atmosAcctID, *atmosPassword, nil),
+		date:     date,
+		gig: event.Event{
+			Title:       *venueAddr,
+			Location:    *venueAddr,
+			AirCode:     edge.Code,
+			City:        edge.CityCode,
+			Country:     edge.Country,
+			CountryCode: edge.CountryCode,
+			TimeZone:    edge.Timezone,
+		},
+	}
+	if *routesFile != "" {
+		est.routes, err = flight.LoadRoutes(*routesFile)
+		errFail(err)
+	}
+	var entries []registry.Entry
+	if *artistFile != "" {
+		entries, err = registry.Load(*artistFile)
+		errFail(err)
+	}
+
+	var bookings []booking
+	booked := make(map[string]bool)
+	for _, spec := range strings.Split(*lineup, ";") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		artist, err := est.resolve(spec, entries)
+		if err != nil {
+			fmt.Printf("%s: %v\n", spec, err)
+			continue
+		}
+		booked[artist.Name] = true
+		b, err := est.estimate(artist)
+		if err != nil {
+			fmt.Printf("%s: %v\n", spec, err)
+			continue
+		}
+		bookings = append(bookings, b)
+	}
+	shareOut(bookings)
+
+	for _, entry := range regional(entries, est.gig.CountryCode, booked, *alternatives) {
+		artist, err := est.fromEntry(entry)
+		if err != nil {
+			fmt.Printf("%s: %v\n", entry.Name, err)
+			continue
+		}
+		b, err := est.estimate(artist)
+		if err != nil {
+			fmt.Printf("%s: %v\n", entry.Name, err)
+			continue
+		}
+		b.alternative = true
+		bookings = append(bookings, b)
+	}
+
+	report(bookings, edge.Code)
+	if *outputFile != "" {
+		errFail(write(bookings, *outputFile))
+	}
+}
+
+// Finds an artist by registry name, otherwise takes the spec as their home city.
+func (est estimator) resolve(spec string, entries []registry.Entry) (ra.Artist, error) {
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Name, spec) {
+			return est.fromEntry(entry)
+		}
+	}
+	parts := strings.SplitN(spec, ",", 2)
+	if len(parts) != 2 {
+		return ra.Artist{}, fmt.Errorf("not in the registry, give a home city as \"City, Country\"")
+	}
+	city, country := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+	airCode, err := est.airSvc.AirCodeByCity(city, country)
+	if err != nil || airCode == "" {
+		return ra.Artist{}, fmt.Errorf("no airport found for %s", spec)
+	}
+	return ra.Artist{Name: spec, City: city, Country: country, AirCode: airCode, Members: 1}, nil
+}
+
+func (est estimator) fromEntry(entry registry.Entry) (ra.Artist, error) {
+	airCode := entry.HomeAirport
+	if airCode == "" {
+		airCode, _ = est.airSvc.AirCodeByCity(entry.City, entry.Country)
+	}
+	if airCode == "" {
+		return ra.Artist{}, fmt.Errorf("no home airport for %s", entry.Name)
+	}
+	return ra.Artist{
+		Name:    entry.Name,
+		City:    entry.City,
+		Country: entry.Country,
+		AirCode: airCode,
+		Members: entry.Members,
+		Crew:    entry.Crew,
+	}, nil
+}
+
+// Plans the round trip to the gig and works out its emissions.
+func (est estimator) estimate(artist ra.Artist) (booking, error) {
+	b := booking{artist: artist}
+	artist.Events = ra.Events{est.date: {est.gig}}
+	trips, err := est.planner.Plan(artist)
+	if err != nil {
+		return b, err
+	}
+	if len(trips) == 0 {
+		return b, nil
+	}
+	if est.routes != nil {
+		trips = est.routes.Connect(trips)
+	}
+	outputs, err := est.atmosSvc.Calculate(context.Background(), trips)
+	if err != nil {
+		return b, err
+	}
+	for _, o := range outputs {
+		b.flights++
+		b.carbon += o.CarbonOutput
+	}
+	return b, nil
+}
+
+// Works out each booking's share of the lineup total, largest first.
+func shareOut(bookings []booking) {
+	var total float64
+	for _, b := range bookings {
+		total += b.carbon
+	}
+	sort.Slice(bookings, func(i, j int) bool {
+		return bookings[i].carbon > bookings[j].carbon
+	})
+	if total == 0 {
+		return
+	}
+	for i := range bookings {
+		bookings[i].share = bookings[i].carbon / total
+	}
+}
+
+/*
+regional picks registry artists based in the same part of the world as the
+venue (the same UN subregion, e.g. Western Europe), who are not already
+booked. Artists from the venue's own country come first, then those with
+the most gigs, as the likeliest to draw a similar crowd.
+*/
+func regional(entries []registry.Entry, countryCode string, booked map[string]bool, n int) []registry.Entry {
+	regions := region.New()
+	venue, ok := regions.Lookup(countryCode)
+	if !ok || n <= 0 {
+		return nil
+	}
+	var candidates []registry.Entry
+	local := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Excluded != "" || booked[entry.Name] {
+			continue
+		}
+		home, ok := regions.Lookup(entry.Country)
+		if !ok || home.Subregion != venue.Subregion {
+			continue
+		}
+		local[entry.Name] = home.Code == venue.Code
+		candidates = append(candidates, entry)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if local[a.Name] != local[b.Name] {
+			return local[a.Name]
+		}
+		return a.EventsTotal > b.EventsTotal
+	})
+	if len(candidates) > n {
+		candidates = candidates[:n]
+	}
+	return candidates
+}
+
+func report(bookings []booking, venueCode string) {
+	fmt.Printf("| %s | %s | %s | %s | %s |\n", "Artist", "Home", "Flights", "Carbon (kg)", "Share of lineup")
+	for _, b := range bookings {
+		if b.alternative {
+			continue
+		}
+		note := ""
+		if b.share >= dominantShare {
+			note = " dominates"
+		}
+		fmt.Printf("| %s | %s | %d | %f | %.0f%%%s |\n", b.artist.Name, b.artist.AirCode, b.flights, b.carbon, b.share*100, note)
+	}
+	fmt.Printf("\nRegional alternatives near %s:\n", venueCode)
+	for _, b := range bookings {
+		if b.alternative {
+			fmt.Printf("| %s | %s | %d | %f |\n", b.artist.Name, b.artist.AirCode, b.flights, b.carbon)
+		}
+	}
+}
+
+// Named like the flight files' columns, figures in the unit the name gives.
+var headers = []string{"artist", "home_airport", "flights", "carbon_kg", "share", "alternative"}
+
+func write(bookings []booking, fName string) error {
+	csvfile, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer csvfile.Close()
+	csvwriter := csv.NewWriter(csvfile)
+	csvwriter.Write(headers)
+	for _, b := range bookings {
+		share := ""
+		if !b.alternative {
+			share = fmt.Sprintf("%f", b.share)
+		}
+		csvwriter.Write([]string{
+			b.artist.Name,
+			b.artist.AirCode,
+			fmt.Sprintf("%d", b.flights),
+			fmt.Sprintf("%f", b.carbon),
+			share,
+			fmt.Sprintf("%t", b.alternative),
+		})
+	}
+	csvwriter.Flush()
+	return csvwriter.Error()
+}
+
+func parseFlags() {
+	flag.Parse()
+	if *venueAddr == "" {
+		log.Fatal("missing venue to estimate the lineup for")
+	}
+	if *gigDate == "" {
+		log.Fatal("missing date of the gig")
+	}
+	if *lineup == "" {
+		log.Fatal("missing lineup of artists")
+	}
+	if *airportFile == "" {
+		log.Fatal("missing pre-compiled list of airports")
+	}
+	if *googleApiKey == "" {
+		log.Fatal("missing googlepai key to find nearest airport")
+	}
+	if *atmosAcctID == "" {
+		log.Fatal("atmosfaire account id for carbon emissions api")
+	}
+	if *atmosPassword == "" {
+		log.Fatal("atmosfaire password for carbon emissions api")
+	}
+	if *edgeApiKey == "" {
+		log.Fatal("edge api key missing for nearest aircode")
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/region"
+	"github.com/cleanscene.flights/lib/registry"
+)
+
+// Emissions of 100 kg a passenger for every flight.
+type fakeFair struct{ atmos.AtmosFair }
+
+func (fakeFair) Calculate(_ context.Context, trips flight.Trips) ([]atmos.Output, error) {
+	var outputs []atmos.Output
+	for _, trip := range trips {
+		outputs = append(outputs, atmos.Output{DepartCode: trip.DepCode, ArrivalCode: trip.ArrCode, CarbonOutput: 100 * float64(trip.Passengers)})
+	}
+	return outputs, nil
+}
+
+func TestEstimate(t *testing.T) {
+	est := estimator{
+		planner:  flight.NewPlanner(region.New(), flight.DefaultSchedule()),
+		atmosSvc: fakeFair{},
+		date:     time.Date(2019, 6, 14, 0, 0, 0, 0, time.UTC),
+		gig:      event.Event{Title: "Fabric", AirCode: "LHR", CountryCode: "GB"},
+	}
+	tests := []struct {
+		name    string
+		artist  ra.Artist
+		flights int
+		carbon  float64
+	}{
+		{"flies in and home", ra.Artist{Name: "Tiga", AirCode: "YUL", Country: "Canada"}, 2, 200},
+		{"with crew", ra.Artist{Name: "Duo", AirCode: "TXL", Country: "Germany", Members: 2, Crew: 1}, 2, 600},
+		{"local", ra.Artist{Name: "Local", AirCode: "LHR", Country: "UK"}, 0, 0},
+	}
+	for _, tt := range tests {
+		b, err := est.estimate(tt.artist)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.flights != tt.flights || b.carbon != tt.carbon {
+			t.Errorf("%s: %d flights %.0f kg, want %d flights %.0f kg", tt.name, b.flights, b.carbon, tt.flights, tt.carbon)
+		}
+	}
+}
+
+func TestShareOut(t *testing.T) {
+	bookings := []booking{
+		{artist: ra.Artist{Name: "A"}, carbon: 100},
+		{artist: ra.Artist{Name: "B"}, carbon: 300},
+		{artist: ra.Artist{Name: "C"}},
+	}
+	shareOut(bookings)
+	want := []struct {
+		name  string
+		share float64
+	}{{"B", 0.75}, {"A", 0.25}, {"C", 0}}
+	for i, w := range want {
+		if bookings[i].artist.Name != w.name || bookings[i].share != w.share {
+			t.Errorf("booking %d = %s %.2f, want %s %.2f", i, bookings[i].artist.Name, bookings[i].share, w.name, w.share)
+		}
+	}
+
+	none := []booking{{artist: ra.Artist{Name: "A"}}}
+	shareOut(none)
+	if none[0].share != 0 {
+		t.Errorf("share %.2f of nothing", none[0].share)
+	}
+}
+
+func TestRegional(t *testing.T) {
+	entries := []registry.Entry{
+		{Name: "Booked", Country: "United Kingdom", EventsTotal: 90},
+		{Name: "Irish", Country: "Ireland", EventsTotal: 80},
+		{Name: "Busy Brit", Country: "United Kingdom", EventsTotal: 50},
+		{Name: "Brit", Country: "UK", EventsTotal: 10},
+		{Name: "Excluded", Country: "United Kingdom", EventsTotal: 70, Excluded: registry.NoHometown},
+		{Name: "Dutch", Country: "Netherlands", EventsTotal: 100},
+		{Name: "Nowhere", Country: "Atlantis", EventsTotal: 100},
+	}
+	booked := map[string]bool{"Booked": true}
+	tests := []struct {
+		country string
+		n       int
+		want    string
+	}{
+		// Northern Europe, the venue's own country first, then by gigs.
+		{"GB", 5, "Busy Brit, Brit, Irish"},
+		{"GB", 2, "Busy Brit, Brit"},
+		{"IE", 5, "Irish, Busy Brit, Brit"},
+		{"NL", 5, "Dutch"},
+		{"XX", 5, ""},
+		{"GB", 0, ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, e := range regional(entries, tt.country, booked, tt.n) {
+			got = append(got, e.Name)
+		}
+		if strings.Join(got, ", ") != tt.want {
+			t.Errorf("regional(%s, %d) = %q, want %q", tt.country, tt.n, strings.Join(got, ", "), tt.want)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lineup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fName := filepath.Join(dir, "lineup.csv")
+	bookings := []booking{
+		{artist: ra.Artist{Name: "Tiga", AirCode: "YUL"}, flights: 2, carbon: 1500.5, share: 1},
+		{artist: ra.Artist{Name: "Brit", AirCode: "LHR"}, alternative: true},
+	}
+	if err := write(bookings, fName); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "artist,home_airport,flights,carbon_kg,share,alternative\n" +
+		"Tiga,YUL,2,1500.500000,1.000000,false\n" +
+		"Brit,LHR,0,0.000000,,true\n"
+	if string(data) != want {
+		t.Errorf("got\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/output"
+	"github.com/cleanscene.flights/lib/store"
+)
+
+// Renders the results as a static site, every page standing on its own with
+// no scripts or styles fetched from elsewhere, for publishing anywhere.
+var (
+	inputDir     = flag.String("input.dir", "./done/artist-pages", "directory of per-artist flight files written by fly")
+	storeFile    = flag.String("store", "", "optional sqlite store written by fly -store.file, to read flights from instead")
+	scenario     = flag.String("scenario", "", "scenario in the store to report")
+	scheduleFile = flag.String("schedule.file", "./done/work/schedule.json", "planner schedule fly ran with, written to its work dir, for the methodology page")
+	outputDir    = flag.String("output.dir", "./done/site", "directory to write the site to")
+	title        = flag.String("title", "RA Top 1000 flights", "title of the site")
+)
+
+var errFail = func(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	flag.Parse()
+	if *storeFile != "" && *scenario == "" {
+		log.Fatal("missing scenario to report from the store")
+	}
+	var (
+		byArtist map[string][]output.Flight
+		err      error
+	)
+	if *storeFile != "" {
+		byArtist, err = fromStore()
+	} else {
+		byArtist, err = fromDir()
+	}
+	errFail(err)
+	if len(byArtist) == 0 {
+		log.Fatal("no flights to report")
+	}
+	schedule, err := loadSchedule(*scheduleFile)
+	errFail(err)
+
+	s := newSite(*title, schedule, byArtist)
+	errFail(s.write(*outputDir))
+	fmt.Printf("%d artists written to %s\n", len(s.Artists), filepath.Join(*outputDir, "index.html"))
+}
+
+func fromStore() (map[string][]output.Flight, error) {
+	db, err := store.OpenReadOnly(*storeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	return db.Flights(*scenario)
+}
+
+func fromDir() (map[string][]output.Flight, error) {
+	infos, err := ioutil.ReadDir(*inputDir)
+	if err != nil {
+		return nil, err
+	}
+	byArtist := make(map[string][]output.Flight)
+	for _, info := range infos {
+		ext := filepath.Ext(info.Name())
+		if info.IsDir() || (ext != ".csv" && !output.IsJSONLines(info.Name())) {
+			continue
+		}
+		flights, err := output.ReadFlights(filepath.Join(*inputDir, info.Name()))
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		name := strings.TrimSuffix(info.Name(), ext)
+		byArtist[name] = append(byArtist[name], flights...)
+	}
+	for name := range byArtist {
+		flights := byArtist[name]
+		sort.SliceStable(flights, func(i, j int) bool { return flights[i].Date < flights[j].Date })
+	}
+	return byArtist, nil
+}
+
+// The schedule fly ran with, the default one if it has not written it.
+func loadSchedule(fName string) (flight.Schedule, error) {
+	schedule := flight.DefaultSchedule()
+	data, err := ioutil.ReadFile(fName)
+	if os.IsNotExist(err) {
+		fmt.Printf("%s not found, describing the default schedule\n", fName)
+		return schedule, nil
+	}
+	if err != nil {
+		return schedule, err
+	}
+	if err := json.Unmarshal(data, &schedule); err != nil {
+		return schedule, fmt.Errorf("%s: %v", fName, err)
+	}
+	return schedule, nil
+}
@@ -0,0 +1,304 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cleanscene.flights/lib/chart"
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/output"
+)
+
+// Artists shown in the leaderboard chart, the table lists them all.
+const chartTopN = 20
+
+type totals struct {
+	Flights     int
+	CarbonKg    float64
+	PerPersonKg float64
+	DistanceKm  float64
+	OffsetEUR   float64
+}
+
+func (t *totals) add(f output.Flight) {
+	t.CarbonKg += f.CarbonKg
+	t.PerPersonKg += f.CarbonPerPersonKg
+	t.DistanceKm += float64(f.DistanceKm)
+	t.OffsetEUR += f.OffsetEUR
+	if f.Departure != f.Arrival && f.CarbonKg > 0 {
+		t.Flights++
+	}
+}
+
+type artistPage struct {
+	Rank   int
+	Name   string
+	ID     string
+	Totals totals
+	Legs   []output.Flight
+	Chart  template.HTML
+}
+
+type site struct {
+	Title       string
+	Generated   string
+	Totals      totals
+	Artists     []artistPage
+	Chart       template.HTML
+	Rules       []string
+	Methodology []string
+}
+
+func newSite(title string, schedule flight.Schedule, byArtist map[string][]output.Flight) site {
+	s := site{
+		Title:     title,
+		Generated: time.Now().UTC().Format("2006-01-02"),
+		Rules:     schedule.Rules(),
+	}
+	methods := make(map[string]bool)
+	for name, flights := range byArtist {
+		page := artistPage{Name: name, Legs: flights}
+		var monthly [12]float64
+		for _, f := range flights {
+			page.Totals.add(f)
+			s.Totals.add(f)
+			if f.Methodology != "" {
+				methods[f.Methodology] = true
+			}
+			if d, err := time.Parse("2006-01-02", f.Date); err == nil {
+				monthly[d.Month()-1] += f.CarbonKg
+			}
+		}
+		page.Chart = template.HTML(chart.Line("CO2 by month", monthLabels, []chart.Series{{Name: name, Values: monthly[:]}}, "kg CO2"))
+		s.Artists = append(s.Artists, page)
+	}
+	for m := range methods {
+		s.Methodology = append(s.Methodology, m)
+	}
+	sort.Strings(s.Methodology)
+
+	sort.Slice(s.Artists, func(i, j int) bool {
+		if s.Artists[i].Totals.CarbonKg != s.Artists[j].Totals.CarbonKg {
+			return s.Artists[i].Totals.CarbonKg > s.Artists[j].Totals.CarbonKg
+		}
+		return s.Artists[i].Name < s.Artists[j].Name
+	})
+	var (
+		labels []string
+		values []float64
+	)
+	taken := make(map[string]bool)
+	for i := range s.Artists {
+		s.Artists[i].Rank = i + 1
+		s.Artists[i].ID = pageID(s.Artists[i].Name, i+1, taken)
+		if i < chartTopN {
+			labels = append(labels, s.Artists[i].Name)
+			values = append(values, s.Artists[i].Totals.CarbonKg)
+		}
+	}
+	s.Chart = template.HTML(chart.Bars("Highest emitting artists", labels, values, "kg CO2"))
+	return s
+}
+
+/*
+Names artists' pages by output.ArtistID, which is empty for names with no
+letters or digits and the same for names differing only in punctuation.
+Those get the slug, or "artist", with their rank added instead.
+*/
+func pageID(name string, rank int, taken map[string]bool) string {
+	id := output.ArtistID(name)
+	if id == "" || taken[id] {
+		slug := id
+		if slug == "" {
+			slug = "artist"
+		}
+		id = fmt.Sprintf("%s-%d", slug, rank)
+		for n := 2; taken[id]; n++ {
+			id = fmt.Sprintf("%s-%d-%d", slug, rank, n)
+		}
+	}
+	taken[id] = true
+	return id
+}
+
+var monthLabels = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// Writes index.html, methodology.html and a page per artist under artists/.
+func (s site) write(dir string) error {
+	if err := os.MkdirAll(filepath.Join(dir, "artists"), 0755); err != nil {
+		return err
+	}
+	type page struct {
+		site
+		Root   string
+		Artist artistPage
+	}
+	if err := render(filepath.Join(dir, "index.html"), "index", page{site: s}); err != nil {
+		return err
+	}
+	if err := render(filepath.Join(dir, "methodology.html"), "methodology", page{site: s}); err != nil {
+		return err
+	}
+	for _, a := range s.Artists {
+		fName := filepath.Join(dir, "artists", a.ID+".html")
+		if err := render(fName, "artist", page{site: s, Root: "../", Artist: a}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func render(fName, name string, data interface{}) error {
+	file, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := pages.ExecuteTemplate(file, name, data); err != nil {
+		return fmt.Errorf("%s: %v", fName, err)
+	}
+	return nil
+}
+
+// 1234567.8 as "1,234,568".
+func thousands(v float64) string {
+	s := fmt.Sprintf("%.0f", math.Abs(v))
+	var b strings.Builder
+	if v < 0 && s != "0" {
+		b.WriteByte('-')
+	}
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+var pages = template.Must(template.New("").Funcs(template.FuncMap{
+	"thousands": thousands,
+	"raw":       func(f float64) string { return fmt.Sprintf("%.3f", f) },
+	"tonnes":    func(kg float64) string { return fmt.Sprintf("%.1f", kg/1000) },
+}).Parse(layout + index + artist + methodology))
+
+const layout = `
+{{define "head"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; color: #222; }
+nav a { margin-right: 1rem; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: .3rem .5rem; border-bottom: 1px solid #ddd; text-align: left; }
+td.n, th.n { text-align: right; }
+th[data-sort] { cursor: pointer; }
+.chart { width: 100%; height: auto; }
+.summary span { display: inline-block; margin-right: 2rem; }
+.summary b { display: block; font-size: 1.6rem; }
+</style>
+</head>
+<body>
+<nav><a href="{{.Root}}index.html">Leaderboard</a><a href="{{.Root}}methodology.html">Methodology</a></nav>
+{{end}}
+
+{{define "summary"}}<p class="summary">
+<span><b>{{.Flights}}</b>flights</span>
+<span><b>{{tonnes .CarbonKg}} t</b>CO2</span>
+<span><b>{{thousands .DistanceKm}} km</b>flown</span>
+<span><b>€{{thousands .OffsetEUR}}</b>to offset</span>
+</p>{{end}}
+
+{{define "foot"}}<footer><p>Generated {{.Generated}}. Figures from the atmosfair flight emissions api, see the <a href="{{.Root}}methodology.html">methodology</a>.</p></footer>
+</body>
+</html>
+{{end}}
+`
+
+// The leaderboard sorts on a header click with a few lines of inline script,
+// by each cell's data-value.
+const index = `
+{{define "index"}}{{template "head" .}}
+<h1>{{.Title}}</h1>
+{{template "summary" .Totals}}
+{{.Chart}}
+<h2>Leaderboard</h2>
+<table id="leaderboard">
+<thead><tr>
+<th class="n" data-sort="n">#</th><th data-sort="s">Artist</th><th class="n" data-sort="n">Flights</th>
+<th class="n" data-sort="n">CO2 (kg)</th><th class="n" data-sort="n">CO2 per person (kg)</th>
+<th class="n" data-sort="n">Distance (km)</th><th class="n" data-sort="n">Offset (€)</th>
+</tr></thead>
+<tbody>
+{{range .Artists}}<tr>
+<td class="n" data-value="{{.Rank}}">{{.Rank}}</td>
+<td data-value="{{.Name}}"><a href="artists/{{.ID}}.html">{{.Name}}</a></td>
+<td class="n" data-value="{{.Totals.Flights}}">{{.Totals.Flights}}</td>
+<td class="n" data-value="{{raw .Totals.CarbonKg}}">{{thousands .Totals.CarbonKg}}</td>
+<td class="n" data-value="{{raw .Totals.PerPersonKg}}">{{thousands .Totals.PerPersonKg}}</td>
+<td class="n" data-value="{{raw .Totals.DistanceKm}}">{{thousands .Totals.DistanceKm}}</td>
+<td class="n" data-value="{{raw .Totals.OffsetEUR}}">{{thousands .Totals.OffsetEUR}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+<script>
+document.querySelectorAll("#leaderboard th[data-sort]").forEach(function (th, col) {
+  var asc = false;
+  th.addEventListener("click", function () {
+    var body = document.querySelector("#leaderboard tbody");
+    var numeric = th.dataset.sort === "n";
+    asc = !asc;
+    Array.from(body.rows).sort(function (a, b) {
+      var x = a.cells[col].dataset.value, y = b.cells[col].dataset.value;
+      var c = numeric ? parseFloat(x) - parseFloat(y) : x.localeCompare(y);
+      return asc ? c : -c;
+    }).forEach(function (row) { body.appendChild(row); });
+  });
+});
+</script>
+{{template "foot" .}}{{end}}
+`
+
+const artist = `
+{{define "artist"}}{{template "head" .}}
+<h1>{{.Artist.Name}}</h1>
+<p>Ranked {{.Artist.Rank}} of {{len .Artists}} by flight CO2.</p>
+{{template "summary" .Artist.Totals}}
+{{.Artist.Chart}}
+<h2>Itinerary</h2>
+<table>
+<thead><tr><th>Date</th><th>Flight</th><th>Leg</th><th>From</th><th>To</th><th class="n">CO2 (kg)</th><th class="n">Distance (km)</th></tr></thead>
+<tbody>
+{{range .Artist.Legs}}<tr>
+<td>{{.Date}}</td>
+<td>{{.Departure}} → {{.Arrival}}{{if .Via}} (via {{.Via}}){{end}}</td>
+<td>{{.Leg}}</td>
+<td>{{if .FromEvent}}{{.FromEvent}}{{if .FromVenue}}, {{.FromVenue}}{{end}}{{else}}home{{end}}</td>
+<td>{{if .ToEvent}}{{.ToEvent}}{{if .ToVenue}}, {{.ToVenue}}{{end}}{{else}}home{{end}}</td>
+<td class="n">{{thousands .CarbonKg}}</td>
+<td class="n">{{.DistanceKm}}</td>
+</tr>
+{{end}}</tbody>
+</table>
+{{template "foot" .}}{{end}}
+`
+
+const methodology = `
+{{define "methodology"}}{{template "head" .}}
+<h1>Methodology</h1>
+<p>Each artist's gigs were placed at the international airport closest to the venue, and their home at the airport in or closest to their home town. Flights between them were planned as follows:</p>
+<ol>
+{{range .Rules}}<li>{{.}}</li>
+{{end}}</ol>
+<p>Emissions of every flight were worked out for economy class by the atmosfair flight emissions api{{if .Methodology}} ({{range $i, $m := .Methodology}}{{if $i}}, {{end}}{{$m}}{{end}}){{end}}, for everyone flying, the artist and their crew. Gigs that could not be placed at an airport, and flights atmosfair returned no emissions for, are left out.</p>
+{{template "foot" .}}{{end}}
+`
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/output"
+)
+
+func TestPageIDs(t *testing.T) {
+	byArtist := map[string][]output.Flight{
+		"DJ Koze": {{CarbonKg: 300}},
+		"DJ-Koze": {{CarbonKg: 200}},
+		"!!!":     {{CarbonKg: 100}},
+	}
+	s := newSite("Test", flight.DefaultSchedule(), byArtist)
+	want := map[string]string{"DJ Koze": "dj-koze", "DJ-Koze": "dj-koze-2", "!!!": "artist-3"}
+	for _, a := range s.Artists {
+		if a.ID != want[a.Name] {
+			t.Errorf("%s: page %q, want %q", a.Name, a.ID, want[a.Name])
+		}
+	}
+}
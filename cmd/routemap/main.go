@@ -0,0 +1,131 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cleanscene.flights/lib/airports"
+	"github.com/cleanscene.flights/lib/output"
+	"github.com/cleanscene.flights/lib/routemap"
+	"github.com/cleanscene.flights/lib/store"
+)
+
+// Draws the artists' flights as great-circle arcs for maps, GeoJSON for web
+// maps and KML for Google Earth.
+var (
+	inputDir   = flag.String("input.dir", "./done/artist-pages", "directory of per-artist flight files written by fly")
+	storeFile  = flag.String("store", "", "optional sqlite store written by fly -store.file, to read flights from instead")
+	scenario   = flag.String("scenario", "", "scenario in the store to draw")
+	artist     = flag.String("artist", "", "only draw this artist's flights, all of them otherwise")
+	coordsFile = flag.String("airport.coords", os.Getenv("AIRPORT_COORDS"), "airport-codes csv from https://datahub.io/core/airport-codes with airport coordinates")
+	outputFile = flag.String("output", "./done/routes.geojson", "file to write, KML for a .kml file and GeoJSON otherwise")
+	points     = flag.Int("points", 32, "least number of segments each arc is drawn with")
+)
+
+var errFail = func(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	parseFlags()
+	coords, err := airports.LoadCoords(*coordsFile)
+	errFail(err)
+
+	var flights []output.Flight
+	if *storeFile != "" {
+		flights, err = fromStore()
+	} else {
+		flights, err = fromDir()
+	}
+	errFail(err)
+	if len(flights) == 0 {
+		log.Fatal("no flights to draw")
+	}
+
+	routes, missing := routemap.Build(flights, coords, *points)
+	if len(missing) > 0 {
+		fmt.Printf("No coordinates for %s, their flights are left out\n", strings.Join(missing, ", "))
+	}
+	file, err := os.Create(*outputFile)
+	errFail(err)
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(*outputFile), ".kml") {
+		name := "Artist flights"
+		if *artist != "" {
+			name = *artist + " flights"
+		}
+		errFail(routemap.WriteKML(file, name, routes))
+	} else {
+		errFail(routemap.WriteGeoJSON(file, routes))
+	}
+	fmt.Printf("%d of %d flights drawn to %s\n", len(routes), len(flights), *outputFile)
+}
+
+func fromStore() ([]output.Flight, error) {
+	db, err := store.OpenReadOnly(*storeFile)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	byArtist, err := db.Flights(*scenario)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for name := range byArtist {
+		if *artist == "" || strings.EqualFold(name, *artist) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	var flights []output.Flight
+	for _, name := range names {
+		flights = append(flights, byArtist[name]...)
+	}
+	return flights, nil
+}
+
+func fromDir() ([]output.Flight, error) {
+	infos, err := ioutil.ReadDir(*inputDir)
+	if err != nil {
+		return nil, err
+	}
+	var flights []output.Flight
+	for _, info := range infos {
+		ext := filepath.Ext(info.Name())
+		if info.IsDir() || (ext != ".csv" && !output.IsJSONLines(info.Name())) {
+			continue
+		}
+		if *artist != "" && !strings.EqualFold(strings.TrimSuffix(info.Name(), ext), *artist) {
+			continue
+		}
+		read, err := output.ReadFlights(filepath.Join(*inputDir, info.Name()))
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		flights = append(flights, read...)
+	}
+	return flights, nil
+}
+
+func parseFlags() {
+	flag.Parse()
+	if *coordsFile == "" {
+		log.Fatal("missing airport-codes csv to place airports with")
+	}
+	if *storeFile != "" && *scenario == "" {
+		log.Fatal("missing scenario to draw from the store")
+	}
+	if *points < 1 {
+		log.Fatal("arcs need at least one segment")
+	}
+}
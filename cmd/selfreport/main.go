@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/cleanscene.flights/lib/airports"
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/gigfile"
+	"github.com/cleanscene.flights/lib/google"
+	"github.com/cleanscene.flights/lib/ical"
+	"github.com/cleanscene.flights/lib/output"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/region"
+)
+
+// Runs an artist's own gig list through the same pipeline as the RA top
+// 1000, so their numbers are comparable with the study.
+var (
+	gigsFile    = flag.String("gigs", "", "the artist's gig list, csv, json or an ical file or url")
+	artistName  = flag.String("name", "", "artist name, used for the output file")
+	homeCity    = flag.String("city", "", "city the artist is based in")
+	homeCountry = flag.String("country", "", "country the artist is based in")
+	homeAirport = flag.String("home.airport", "", "iata code of the artist's home airport, instead of looking up the city")
+	members     = flag.Int("members", 1, "number of people in the act")
+	crew        = flag.Int("crew", 0, "number of crew travelling with the act")
+	tourYear    = flag.String("tour.year", "", "only count gigs in this year, all gigs if empty")
+	outputDir   = flag.String("output.dir", "./done/self-reports", "directory to write flight data csv output to")
+	airportFile = flag.String("airport.inputs", os.Getenv("AIRPORT_INPUT"), "precompiled list of major airpot codes and their major city")
+	routesFile  = flag.String("routes.inputs", os.Getenv("ROUTES_INPUT"), "optional openflights routes.dat, used to infer connections where there is no direct flight")
+
+	googleApiKey  = flag.String("google.apikey", os.Getenv("GOOGLE_API_KEY"), "google api key for airports svc")
+	atmosAcctID   = flag.String("atmos.acctID", os.Getenv("ATMOS_ACCOUNT_ID"), "account id for atmosfaire api")
+	atmosPassword = flag.String("atmos.pass", os.Getenv("ATMOS_PASSWORD"), "password for atmosfaire api")
+	edgeApiKey    = flag.String("edge.apiKey", os.Getenv("EDGE_API_KEY"), "key for edge api to find nearst airport code")
+)
+
+// Atmosfair api url use for flight emission calculations.
+const atmosUrl = "https://api.atmosfair.de/api/emission/flight"
+
+var errFail = func(err error) {
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	parseFlags()
+	errFail(os.MkdirAll(*outputDir, 0755))
+
+	googleApi := google.NewApi(*googleApiKey, nil)
+	airSvc, err := airports.New(*airportFile, *edgeApiKey, googleApi, nil)
+	errFail(err)
+
+	gigs := gigfile.New(*gigsFile)
+	if isCalendar(*gigsFile) {
+		gigs = ical.New(*gigsFile)
+	}
+	raSvc := ra.New(airSvc, gigs, nil, nil, *outputDir, *tourYear)
+	planner := flight.NewPlanner(region.New(), flight.DefaultSchedule())
+	atmosSvc := atmos.NewFair(atmosUrl, *atmosAcctID, *atmosPassword, nil)
+
+	airCode := *homeAirport
+	if airCode == "" {
+		airCode, err = airSvc.AirCodeByCity(*homeCity, *homeCountry)
+		errFail(err)
+	}
+	artist := ra.Artist{
+		Name:    *artistName,
+		City:    *homeCity,
+		Country: *homeCountry,
+		Link:    *gigsFile,
+		AirCode: airCode,
+		Members: *members,
+		Crew:    *crew,
+		Events:  make(ra.Events),
+	}
+
+	events, err := raSvc.LoadEvents(context.Background(), artist)
+	errFail(err)
+	artist.Events = ra.Events(events)
+	trips, err := planner.Plan(artist)
+	errFail(err)
+	if *routesFile != "" {
+		routes, err := flight.LoadRoutes(*routesFile)
+		errFail(err)
+		trips = routes.Connect(trips)
+	}
+	outputs, err := atmosSvc.Calculate(context.Background(), trips)
+	errFail(err)
+
+	fName := fmt.Sprintf("%s/%s.csv", *outputDir, artist.Name)
+	errFail(output.WriteFlights(artist.Name, outputs, fName))
+	var carbon float64
+	for _, o := range outputs {
+		carbon += o.CarbonOutput
+	}
+	fmt.Printf("%d gigs, %d flights, %f kg CO2 written to %s\n", events.Count(), len(outputs), carbon, fName)
+}
+
+func isCalendar(fName string) bool {
+	return strings.HasSuffix(strings.ToLower(fName), ".ics") || strings.HasPrefix(fName, "webcal://")
+}
+
+func parseFlags() {
+	flag.Parse()
+	if *gigsFile == "" {
+		log.Fatal("missing gig list to calculate")
+	}
+	if *artistName == "" {
+		log.Fatal("missing artist name")
+	}
+	if *homeAirport == "" && (*homeCity == "" || *homeCountry == "") {
+		log.Fatal("missing home city and country, or home airport")
+	}
+	if *airportFile == "" {
+		log.Fatal("missing pre-compiled list of airports")
+	}
+	if *googleApiKey == "" {
+		log.Fatal("missing googlepai key to find nearest airport")
+	}
+	if *atmosAcctID == "" {
+		log.Fatal("atmosfaire account id for carbon emissions api")
+	}
+	if *atmosPassword == "" {
+		log.Fatal("atmosfaire password for carbon emissions api")
+	}
+	if *edgeApiKey == "" {
+		log.Fatal("edge api key missing for nearest aircode")
+	}
+}
@@ -4,5 +4,6 @@ go 1.14
 
 require (
 	github.com/PuerkitoBio/goquery v1.5.1
-	github.com/pirsquare/country-mapper v0.0.0-20180107162822-0fffc2d62977
+	github.com/mattn/go-sqlite3 v1.14.22
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -2,24 +2,29 @@ package airports
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"os"
 	"strings"
 
 	"github.com/cleanscene.flights/lib/google"
+	"github.com/cleanscene.flights/lib/throttle"
 )
 
 type Airports interface {
 	AirCodeByCity(string, string) (string, error)
-	FindClosestAirport(string) (Edge, error)
+	FindClosestAirport(context.Context, string) (Edge, error)
+	FindClosestAirportByCoords(context.Context, float64, float64) (Edge, error)
+	Geocode(context.Context, string) (float64, float64, error)
 }
 
 type AirMap map[string]string
 
-func New(fname, edgeKey string, googleApi google.Places) (Airports, error) {
+// New loads the airport list, nearest airports are looked up on aviation-edge
+// through edgeClient, nil for the default client.
+func New(fname, edgeKey string, googleApi google.Places, edgeClient throttle.Client) (Airports, error) {
 	var (
 		airSvc   service
 		airports = make(AirMap)
@@ -42,6 +47,7 @@ func New(fname, edgeKey string, googleApi google.Places) (Airports, error) {
 	airSvc.googleapi = googleApi
 	airSvc.edgeHost = "http://aviation-edge.com/v2/public/nearby?key="
 	airSvc.edgeKey = edgeKey
+	airSvc.edgeClient = edgeClient
 	return airSvc, nil
 }
 
@@ -49,23 +55,26 @@ type service struct {
 	googleapi google.Places
 
 	// Local datastore downloaded from https://datahub.io/core/airport-codes
-	cache    AirMap
-	edgeHost string
-	edgeKey  string
+	cache      AirMap
+	edgeHost   string
+	edgeKey    string
+	edgeClient throttle.Client
 }
 
 type Edge struct {
-	Code     string `json:"codeIataAirport"`
-	Country  string `json:"nameCountry"`
-	CityCode string `json:"codeIataCity"`
+	Code        string `json:"codeIataAirport"`
+	Country     string `json:"nameCountry"`
+	CountryCode string `json:"codeIso2Country"`
+	CityCode    string `json:"codeIataCity"`
+	Timezone    string `json:"timezone"`
 }
 
 type Edges []Edge
 
-func (as service) nearestAirportByCoords(lng, lat float64) (Edges, error) {
+func (as service) nearestAirportByCoords(ctx context.Context, lng, lat float64) (Edges, error) {
 	var edges = Edges{}
 	query := fmt.Sprintf("%s%s&lat=%f&lng=%f&distance=500", as.edgeHost, as.edgeKey, lat, lng)
-	resp, err := http.Get(query)
+	resp, err := throttle.Get(ctx, as.edgeClient, query)
 	if err != nil {
 		return edges, err
 	}
@@ -79,25 +88,34 @@ func (as service) nearestAirportByCoords(lng, lat float64) (Edges, error) {
 
 func isUrl(location string) bool { return strings.Contains(location, "http") }
 
-func (as service) FindClosestAirport(location string) (Edge, error) {
-	var (
-		edges    []Edge
-		lng, lat float64
-		err      error
-	)
-
+// Geocode finds the coordinates of a venue, given as an address or a
+// google maps link.
+func (as service) Geocode(ctx context.Context, location string) (float64, float64, error) {
+	if as.googleapi == nil {
+		return 0, 0, errors.New("no geocoder configured")
+	}
 	if isUrl(location) {
 		coords := strings.Split(location, "?q=")
-		lng, lat, err = as.googleapi.QueryCoordinates(coords[1], google.CoordQuery)
-
-	} else {
-		lng, lat, err = as.googleapi.QueryCoordinates(location, google.TextQuery)
+		if len(coords) < 2 {
+			return 0, 0, errors.New("no coordinates in maps link")
+		}
+		return as.googleapi.QueryCoordinates(ctx, coords[1], google.CoordQuery)
 	}
+	return as.googleapi.QueryCoordinates(ctx, location, google.TextQuery)
+}
 
+func (as service) FindClosestAirport(ctx context.Context, location string) (Edge, error) {
+	lng, lat, err := as.Geocode(ctx, location)
 	if err != nil {
 		return Edge{}, err
 	}
-	edges, err = as.nearestAirportByCoords(lng, lat)
+	return as.FindClosestAirportByCoords(ctx, lng, lat)
+}
+
+// FindClosestAirportByCoords skips geocoding for sources that already know
+// where the venue is.
+func (as service) FindClosestAirportByCoords(ctx context.Context, lng, lat float64) (Edge, error) {
+	edges, err := as.nearestAirportByCoords(ctx, lng, lat)
 	if err != nil {
 		return Edge{}, err
 	}
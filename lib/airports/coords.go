@@ -0,0 +1,126 @@
+package airports
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/cleanscene.flights/lib/geo"
+)
+
+// Coords of airports by IATA code.
+type Coords map[string]geo.Point
+
+// Larger airports win when a code is listed more than once.
+var airportRank = map[string]int{"large_airport": 3, "medium_airport": 2, "small_airport": 1}
+
+/*
+LoadCoords reads airport positions from the airport-codes csv of
+https://datahub.io/core/airport-codes, rows without an IATA code skipped.
+Its coordinates column has been published as both "lng, lat" and
+"lat, lng", the order is told from the values, a longitude beyond ±90
+only fitting one way. The latitude_deg and longitude_deg columns of the
+OurAirports csv it is built from are read too.
+*/
+func LoadCoords(fname string) (Coords, error) {
+	coords := make(Coords)
+	file, err := os.Open(fname)
+	if err != nil {
+		return coords, err
+	}
+	defer file.Close()
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return coords, fmt.Errorf("%s: %v", fname, err)
+	}
+	col := make(map[string]int)
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	if _, ok := col["iata_code"]; !ok {
+		return coords, fmt.Errorf("%s: no iata_code column, expected the datahub airport-codes csv", fname)
+	}
+	_, pair := col["coordinates"]
+	_, split := col["latitude_deg"]
+	if !pair && !split {
+		return coords, fmt.Errorf("%s: no coordinates or latitude_deg column", fname)
+	}
+
+	type row struct {
+		code   string
+		rank   int
+		first  float64
+		second float64
+	}
+	var (
+		rows     []row
+		lngFirst bool
+		latFirst bool
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return coords, fmt.Errorf("%s: %v", fname, err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		code := strings.ToUpper(get("iata_code"))
+		if code == "" {
+			continue
+		}
+		r := row{code: code, rank: airportRank[get("type")]}
+		if split {
+			lat, err1 := strconv.ParseFloat(get("latitude_deg"), 64)
+			lng, err2 := strconv.ParseFloat(get("longitude_deg"), 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			r.first, r.second = lat, lng
+			latFirst = true
+		} else {
+			parts := strings.Split(get("coordinates"), ",")
+			if len(parts) != 2 {
+				continue
+			}
+			a, err1 := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+			b, err2 := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			r.first, r.second = a, b
+			lngFirst = lngFirst || math.Abs(a) > 90
+			latFirst = latFirst || math.Abs(b) > 90
+		}
+		rows = append(rows, r)
+	}
+	if lngFirst && latFirst && !split {
+		return coords, fmt.Errorf("%s: coordinates are in no consistent order", fname)
+	}
+
+	ranks := make(map[string]int)
+	for _, r := range rows {
+		if rank, ok := ranks[r.code]; ok && rank >= r.rank {
+			continue
+		}
+		ranks[r.code] = r.rank
+		if lngFirst {
+			coords[r.code] = geo.Point{Lat: r.second, Lng: r.first}
+		} else {
+			coords[r.code] = geo.Point{Lat: r.first, Lng: r.second}
+		}
+	}
+	return coords, nil
+}
@@ -0,0 +1,130 @@
+package airports
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cleanscene.flights/lib/geo"
+)
+
+func writeCSV(t *testing.T, data string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "airports")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fName := filepath.Join(dir, "airport-codes.csv")
+	if err := ioutil.WriteFile(fName, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fName
+}
+
+/*
+European airports come first, where latitude and longitude both fit either
+way round, the order is settled by a later airport's longitude beyond ±90.
+*/
+func TestLoadCoordsOrder(t *testing.T) {
+	rows := [][3]string{
+		{"TXL", "large_airport", "52.560, 13.288"},
+		{"AMS", "large_airport", "52.309, 4.764"},
+		{"LIS", "large_airport", "38.781, -9.136"},
+		{"NRT", "large_airport", "35.765, 140.386"},
+	}
+	tests := []struct {
+		name     string
+		lngFirst bool
+	}{
+		{"lat, lng", false},
+		{"lng, lat", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			b.WriteString("ident,type,name,iata_code,coordinates\n")
+			for _, r := range rows {
+				coords := r[2]
+				if tt.lngFirst {
+					parts := strings.Split(coords, ", ")
+					coords = parts[1] + ", " + parts[0]
+				}
+				b.WriteString("X," + r[1] + ",," + r[0] + ",\"" + coords + "\"\n")
+			}
+			coords, err := LoadCoords(writeCSV(t, b.String()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := map[string]geo.Point{
+				"TXL": {Lat: 52.560, Lng: 13.288},
+				"AMS": {Lat: 52.309, Lng: 4.764},
+				"LIS": {Lat: 38.781, Lng: -9.136},
+				"NRT": {Lat: 35.765, Lng: 140.386},
+			}
+			for code, p := range want {
+				if coords[code] != p {
+					t.Errorf("%s at %v, want %v", code, coords[code], p)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadCoords(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]geo.Point
+		wantErr string
+	}{
+		{
+			name: "ourairports columns, larger airports win",
+			data: "ident,type,iata_code,latitude_deg,longitude_deg\n" +
+				"EDDB,large_airport,BER,52.362,13.501\n" +
+				"XBER,small_airport,BER,1,1\n" +
+				"NOIATA,small_airport,,2,2\n" +
+				"BAD,medium_airport,XXX,north,13\n",
+			want: map[string]geo.Point{"BER": {Lat: 52.362, Lng: 13.501}},
+		},
+		{
+			name:    "inconsistent order",
+			data:    "type,iata_code,coordinates\nlarge_airport,NRT,\"35.765, 140.386\"\nlarge_airport,LAX,\"-118.408, 33.942\"\n",
+			wantErr: "no consistent order",
+		},
+		{
+			name:    "no iata codes",
+			data:    "ident,coordinates\nEDDB,\"52.3, 13.5\"\n",
+			wantErr: "no iata_code column",
+		},
+		{
+			name:    "no coordinates",
+			data:    "ident,iata_code\nEDDB,BER\n",
+			wantErr: "no coordinates or latitude_deg column",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			coords, err := LoadCoords(writeCSV(t, tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(coords) != len(tt.want) {
+				t.Errorf("got %v, want %v", coords, tt.want)
+			}
+			for code, p := range tt.want {
+				if coords[code] != p {
+					t.Errorf("%s at %v, want %v", code, coords[code], p)
+				}
+			}
+		})
+	}
+}
@@ -2,16 +2,19 @@ package atmos
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/throttle"
 )
 
 type AtmosFair interface {
-	Calculate(flight.Trips) ([]Output, error)
-	Do(AtmosReq) FlightResp
+	Calculate(context.Context, flight.Trips) ([]Output, error)
+	Do(context.Context, AtmosReq) FlightResp
 	NewSyncReq(string, string, string) AtmosReq
 }
 
@@ -19,13 +22,18 @@ type service struct {
 	acctID   string
 	password string
 	host     string
-	cli      *http.Client
+	cli      throttle.Client
 }
 
-func NewFair(host, acctID, password string) AtmosFair {
-	return service{acctID: acctID, password: password, host: host, cli: &http.Client{}}
+// NewFair calls atmosfair through cli, nil for the default client.
+func NewFair(host, acctID, password string, cli throttle.Client) AtmosFair {
+	return service{acctID: acctID, password: password, host: host, cli: throttle.OrDefault(cli)}
 }
 
+// Methodology names how Calculate works out emissions, recorded next to every
+// figure so results from different backends or assumptions are not mixed.
+const Methodology = "atmosfair-flight-api/economy"
+
 type AtmosResp struct {
 	Status      string       `json:"status"`
 	Errors      []string     `json:"errors"`
@@ -60,32 +68,40 @@ type Flight struct {
 	FlightCount   int    `json:"flightCount"`
 }
 
-func (s service) Calculate(trips flight.Trips) ([]Output, error) {
+func (s service) Calculate(ctx context.Context, trips flight.Trips) ([]Output, error) {
 	var outputs = make([]Output, 0)
 	atmosReq := AtmosReq{
 		AccountID: s.acctID,
 		Password:  s.password,
 		Flights:   make([]Flight, 0),
 	}
+	var submitted = make(flight.Trips, 0)
 	for _, trip := range trips {
 		if trip.DepCode == "" || trip.ArrCode == "" {
 			continue
 		}
+		submitted = append(submitted, trip)
 		atmosReq.Flights = append(atmosReq.Flights, Flight{
 			DepartCode:    trip.DepCode,
 			ArrivalCode:   trip.ArrCode,
-			DepartureDate: trip.Date,
+			DepartureDate: trip.Date.Format("2006-01-02"),
 			FlightCount:   1,
-			PassCount:     1,
+			PassCount:     passengers(trip),
 		})
 	}
-	resp, err := s.bulkReq(atmosReq)
+	if len(atmosReq.Flights) == 0 {
+		return outputs, nil
+	}
+	resp, err := s.bulkReq(ctx, atmosReq)
+	if err != nil {
+		return outputs, err
+	}
+	finalFlights, err := lineUp(atmosReq.Flights, s.retryAndMerge(ctx, atmosReq.Flights, resp.Flights))
 	if err != nil {
 		return outputs, err
 	}
-	finalFlights := s.retryAndMerge(resp.Flights)
-	for _, flight := range finalFlights {
-		outputs = append(outputs, Output{
+	for index, flight := range finalFlights {
+		output := Output{
 			ArrivalCode:  flight.ArrivalCode,
 			DepartCode:   flight.DepartCode,
 			FlightDay:    flight.DepartureDate,
@@ -93,30 +109,85 @@ func (s service) Calculate(trips flight.Trips) ([]Output, error) {
 			CarbonOutput: flight.CarbonOutput,
 			FuelInLiter:  flight.FuelInLiter,
 			Distance:     flight.Distance,
-		})
+		}
+		output.Via = submitted[index].Via
+		output.Tour = submitted[index].Tour
+		output.Passengers = passengers(submitted[index])
+		output.From = submitted[index].From
+		output.To = submitted[index].To
+		outputs = append(outputs, output)
 	}
 	return outputs, nil
 }
-func (s service) bulkReq(req AtmosReq) (AtmosResp, error) {
+
+// Route and day a flight is matched on, atmosfair answering with the codes
+// and date it was asked about.
+func flightKey(dep, arr, date string) string {
+	if len(date) > len("2006-01-02") {
+		date = date[:len("2006-01-02")]
+	}
+	return fmt.Sprintf("%s-%s on %s", dep, arr, date)
+}
+
+/*
+lineUp puts the responses in the order the flights were requested, matching
+each by route and date rather than by position: atmosfair may leave flights
+out, and retried ones are spliced back in. A flight without its response,
+or a response nobody asked for, is an error, emissions attributed to the
+wrong gig being worse than none.
+*/
+func lineUp(requested []Flight, flights []FlightResp) ([]FlightResp, error) {
+	unused := make(map[string][]int)
+	for i, f := range flights {
+		key := flightKey(f.DepartCode, f.ArrivalCode, f.DepartureDate)
+		unused[key] = append(unused[key], i)
+	}
+	lined := make([]FlightResp, len(requested))
+	for i, f := range requested {
+		key := flightKey(f.DepartCode, f.ArrivalCode, f.DepartureDate)
+		if len(unused[key]) == 0 {
+			return nil, fmt.Errorf("atmosfair returned no emissions for the flight %s", key)
+		}
+		lined[i] = flights[unused[key][0]]
+		unused[key] = unused[key][1:]
+	}
+	if len(flights) > len(requested) {
+		return nil, fmt.Errorf("atmosfair returned %d flights for %d requested", len(flights), len(requested))
+	}
+	return lined, nil
+}
+func passengers(trip flight.Trip) int {
+	if trip.Passengers < 1 {
+		return 1
+	}
+	return trip.Passengers
+}
+
+func (s service) bulkReq(ctx context.Context, req AtmosReq) (AtmosResp, error) {
 	var atmosResp AtmosResp
 	b := new(bytes.Buffer)
 	json.NewEncoder(b).Encode(req)
 	httpReq, _ := http.NewRequest("POST", s.host, b)
 	httpReq.Header.Set("Accept", "application/json, text/plain, */*")
 	httpReq.Header.Set("Content-Type", "application/json;charset=UTF-8")
-	resp, err := s.cli.Do(httpReq)
-	defer resp.Body.Close()
+	resp, err := s.cli.Do(httpReq.WithContext(ctx))
 	if err != nil {
 		return atmosResp, err
 	}
-	json.NewDecoder(resp.Body).Decode(&atmosResp)
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(&atmosResp); err != nil {
+		return atmosResp, fmt.Errorf("atmosfair: %s: %v", resp.Status, err)
+	}
 	if atmosResp.Status != "SUCCESS" {
-		fmt.Println(atmosResp.Errors[0])
+		if len(atmosResp.Errors) == 0 {
+			return atmosResp, fmt.Errorf("atmosfair: status %q", atmosResp.Status)
+		}
+		return atmosResp, fmt.Errorf("atmosfair: status %q: %s", atmosResp.Status, strings.Join(atmosResp.Errors, "; "))
 	}
 	return atmosResp, nil
 }
 
-func (s service) Do(req AtmosReq) FlightResp {
+func (s service) Do(ctx context.Context, req AtmosReq) FlightResp {
 
 	var atmosResp = AtmosResp{}
 	b := new(bytes.Buffer)
@@ -124,15 +195,12 @@ func (s service) Do(req AtmosReq) FlightResp {
 	httpReq, _ := http.NewRequest("POST", s.host, b)
 	httpReq.Header.Set("Accept", "application/json, text/plain, */*")
 	httpReq.Header.Set("Content-Type", "application/json;charset=UTF-8")
-	resp, err := s.cli.Do(httpReq)
-	if err != nil {
-		fmt.Println(err)
-	}
-	defer resp.Body.Close()
+	resp, err := s.cli.Do(httpReq.WithContext(ctx))
 	if err != nil {
 		fmt.Println(err.Error())
 		return FlightResp{}
 	}
+	defer resp.Body.Close()
 	json.NewDecoder(resp.Body).Decode(&atmosResp)
 	if atmosResp.Status != "SUCCESS" {
 		fmt.Printf("ATMOS ERROR: %v for request: %v\n", atmosResp.Errors, req)
@@ -159,7 +227,7 @@ func (s service) NewSyncReq(dep, arr, date string) AtmosReq {
 	}
 }
 
-func (s service) syncReq(req AtmosReq) []FlightResp {
+func (s service) syncReq(ctx context.Context, req AtmosReq) []FlightResp {
 	var fResp = make([]FlightResp, 0)
 	for _, flight := range req.Flights {
 		rr := AtmosReq{
@@ -167,13 +235,18 @@ func (s service) syncReq(req AtmosReq) []FlightResp {
 			Password:  s.password,
 			Flights:   []Flight{flight},
 		}
-		ff := s.Do(rr)
+		ff := s.Do(ctx, rr)
+		// A failed retry is a flight without emissions, not a missing one.
+		if ff.DepartCode == "" {
+			ff.DepartCode, ff.ArrivalCode, ff.DepartureDate = flight.DepartCode, flight.ArrivalCode, flight.DepartureDate
+		}
 		fResp = append(fResp, ff)
 	}
 	return fResp
 
 }
 
+// Emissions, fuel and offset are for everyone on the flight, the whole act and crew.
 type Output struct {
 	DepartCode   string
 	ArrivalCode  string
@@ -182,12 +255,45 @@ type Output struct {
 	CarbonOutput float64
 	FuelInLiter  float64
 	Distance     int
+	Via          string
+	Tour         string
+	Passengers   int
+	// Gigs the flight is attributed to, see AttributedTo.
+	From flight.Gig
+	To   flight.Gig
 }
 
+func (o Output) CarbonPerPerson() float64 {
+	if o.Passengers < 1 {
+		return o.CarbonOutput
+	}
+	return o.CarbonOutput / float64(o.Passengers)
+}
+
+/*
+AttributedTo splits the flight's emissions between the gigs it serves. A
+flight between two gigs counts half towards each, one from or to home counts
+fully towards its only gig. Shares are a fraction of the flight's totals.
+*/
+func (o Output) AttributedTo() map[flight.Gig]float64 {
+	shares := make(map[flight.Gig]float64)
+	switch {
+	case !o.From.IsZero() && !o.To.IsZero():
+		shares[o.From] += 0.5
+		shares[o.To] += 0.5
+	case !o.From.IsZero():
+		shares[o.From] = 1
+	case !o.To.IsZero():
+		shares[o.To] = 1
+	}
+	return shares
+}
+
+// The first run of flights without figures, as a start and an end index.
 func findNullData(flights []FlightResp) (int, int) {
 	var (
 		firstEmptyFlightIdx = 0
-		endEmptyFlightIdx   = len(flights) - 1
+		endEmptyFlightIdx   = len(flights)
 	)
 	for index, flight := range flights {
 		if flight.OffsetInEu == 0 {
@@ -197,7 +303,7 @@ func findNullData(flights []FlightResp) (int, int) {
 	}
 	for index, flight := range flights[firstEmptyFlightIdx:] {
 		if flight.OffsetInEu != 0 {
-			endEmptyFlightIdx = index
+			endEmptyFlightIdx = firstEmptyFlightIdx + index
 			break
 		}
 	}
@@ -209,21 +315,33 @@ func findNullData(flights []FlightResp) (int, int) {
 }
 
 // Seems to be some sort of rate limit or bug with atmosfair, this handles that.
-func (s service) retryAndMerge(firstAttempt []FlightResp) []FlightResp {
+func (s service) retryAndMerge(ctx context.Context, requested []Flight, firstAttempt []FlightResp) []FlightResp {
 	start, end := findNullData(firstAttempt)
+	// Passengers are looked up the way lineUp matches, the response may be
+	// missing flights or be in another order
+	passCounts := make(map[string][]int)
+	for _, f := range requested {
+		key := flightKey(f.DepartCode, f.ArrivalCode, f.DepartureDate)
+		passCounts[key] = append(passCounts[key], f.PassCount)
+	}
 	retryFlights := make([]Flight, 0)
 	emptyFlights := firstAttempt[start:end]
 	for _, flight := range emptyFlights {
+		passCount := 1
+		key := flightKey(flight.DepartCode, flight.ArrivalCode, flight.DepartureDate)
+		if counts := passCounts[key]; len(counts) > 0 {
+			passCount, passCounts[key] = counts[0], counts[1:]
+		}
 		retryFlights = append(retryFlights, Flight{
 			ArrivalCode:   flight.ArrivalCode,
 			DepartCode:    flight.DepartCode,
 			DepartureDate: flight.DepartureDate,
 			FlightCount:   1,
-			PassCount:     1,
+			PassCount:     passCount,
 		})
 
 	}
-	syncResp := s.syncReq(AtmosReq{AccountID: s.acctID, Password: s.password, Flights: retryFlights})
+	syncResp := s.syncReq(ctx, AtmosReq{AccountID: s.acctID, Password: s.password, Flights: retryFlights})
 	finalFlights := append(firstAttempt[:start], syncResp...)
 	finalFlights = append(finalFlights, firstAttempt[end:]...)
 	return finalFlights
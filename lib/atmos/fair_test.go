@@ -0,0 +1,182 @@
+package atmos
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleanscene.flights/lib/flight"
+)
+
+// An atmosfair stand-in answering each bulk request through respond.
+func fakeFair(t *testing.T, respond func([]Flight) []FlightResp) AtmosFair {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req AtmosReq
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		json.NewEncoder(w).Encode(AtmosResp{Status: "SUCCESS", Flights: respond(req.Flights)})
+	}))
+	t.Cleanup(srv.Close)
+	return NewFair(srv.URL, "acct", "secret", nil)
+}
+
+func answer(f Flight) FlightResp {
+	return FlightResp{
+		DepartCode:    f.DepartCode,
+		ArrivalCode:   f.ArrivalCode,
+		DepartureDate: f.DepartureDate,
+		CarbonOutput:  100 * float64(f.PassCount),
+		OffsetInEu:    2,
+		FuelInLiter:   40,
+		Distance:      900,
+	}
+}
+
+func tripsTo(t *testing.T) flight.Trips {
+	date := func(s string) time.Time {
+		d, err := time.Parse("2006-01-02", s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return d
+	}
+	ber := flight.Gig{Date: date("2019-03-01"), Title: "Berghain"}
+	fab := flight.Gig{Date: date("2019-03-02"), Title: "Fabric"}
+	return flight.Trips{
+		{DepCode: "AMS", ArrCode: "TXL", Date: date("2019-02-28"), Passengers: 2, To: ber},
+		{DepCode: "", ArrCode: "TXL", Date: date("2019-03-01")},
+		{DepCode: "TXL", ArrCode: "LHR", Date: date("2019-03-02"), Passengers: 2, Tour: "UK run", From: ber, To: fab},
+		{DepCode: "LHR", ArrCode: "AMS", Date: date("2019-03-03"), Passengers: 3, From: fab},
+	}
+}
+
+func TestCalculateMatchesByRoute(t *testing.T) {
+	// Answers come back in reverse order.
+	fair := fakeFair(t, func(req []Flight) []FlightResp {
+		var resp []FlightResp
+		for i := len(req) - 1; i >= 0; i-- {
+			resp = append(resp, answer(req[i]))
+		}
+		return resp
+	})
+	outputs, err := fair.Calculate(context.Background(), tripsTo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		route, from, to, tour string
+		passengers            int
+		carbon                float64
+	}{
+		{"AMS-TXL", "", "Berghain", "", 2, 200},
+		{"TXL-LHR", "Berghain", "Fabric", "UK run", 2, 200},
+		{"LHR-AMS", "Fabric", "", "", 3, 300},
+	}
+	if len(outputs) != len(want) {
+		t.Fatalf("got %d outputs, want one per trip with both airports", len(outputs))
+	}
+	for i, w := range want {
+		o := outputs[i]
+		if o.DepartCode+"-"+o.ArrivalCode != w.route || o.From.Title != w.from || o.To.Title != w.to ||
+			o.Tour != w.tour || o.Passengers != w.passengers || o.CarbonOutput != w.carbon {
+			t.Errorf("output %d = %s-%s from %q to %q tour %q x%d %.0f kg, want %+v",
+				i, o.DepartCode, o.ArrivalCode, o.From.Title, o.To.Title, o.Tour, o.Passengers, o.CarbonOutput, w)
+		}
+	}
+}
+
+func TestCalculateMissingFlight(t *testing.T) {
+	// The middle flight is left out of the answer.
+	fair := fakeFair(t, func(req []Flight) []FlightResp {
+		return []FlightResp{answer(req[0]), answer(req[2])}
+	})
+	_, err := fair.Calculate(context.Background(), tripsTo(t))
+	if err == nil || !strings.Contains(err.Error(), "TXL-LHR on 2019-03-02") {
+		t.Errorf("got %v, want an error naming the missing flight", err)
+	}
+}
+
+func TestCalculateUnrequestedFlight(t *testing.T) {
+	fair := fakeFair(t, func(req []Flight) []FlightResp {
+		var resp []FlightResp
+		for _, f := range req {
+			resp = append(resp, answer(f))
+		}
+		return append(resp, answer(Flight{DepartCode: "CDG", ArrivalCode: "JFK", DepartureDate: "2019-03-04", PassCount: 1}))
+	})
+	if _, err := fair.Calculate(context.Background(), tripsTo(t)); err == nil {
+		t.Error("a flight nobody asked for is an error")
+	}
+}
+
+// Flights the bulk request returns without figures are retried one by one
+// and spliced back in where they belong.
+func TestCalculateRetried(t *testing.T) {
+	fair := fakeFair(t, func(req []Flight) []FlightResp {
+		var resp []FlightResp
+		for _, f := range req {
+			r := answer(f)
+			if len(req) > 1 && f.DepartCode == "TXL" {
+				r.CarbonOutput, r.OffsetInEu = 0, 0
+			}
+			resp = append(resp, r)
+		}
+		return resp
+	})
+	outputs, err := fair.Calculate(context.Background(), tripsTo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 3 || outputs[1].DepartCode != "TXL" || outputs[1].CarbonOutput == 0 || outputs[1].To.Title != "Fabric" {
+		t.Errorf("retried flight not merged back in place: %+v", outputs)
+	}
+}
+
+// Retried flights keep their passengers when the bulk answer is in another
+// order than the request.
+func TestCalculateRetriedReordered(t *testing.T) {
+	fair := fakeFair(t, func(req []Flight) []FlightResp {
+		var resp []FlightResp
+		for i := len(req) - 1; i >= 0; i-- {
+			r := answer(req[i])
+			if len(req) > 1 && req[i].DepartCode == "LHR" {
+				r.CarbonOutput, r.OffsetInEu = 0, 0
+			}
+			resp = append(resp, r)
+		}
+		return resp
+	})
+	outputs, err := fair.Calculate(context.Background(), tripsTo(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(outputs) != 3 || outputs[2].DepartCode != "LHR" || outputs[2].CarbonOutput != 300 {
+		t.Errorf("LHR-AMS retried for the wrong number of passengers: %+v", outputs)
+	}
+}
+
+func TestCalculateFailed(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"status": "FAILURE"}`))
+	}))
+	defer srv.Close()
+	fair := NewFair(srv.URL, "acct", "secret", nil)
+
+	_, err := fair.Calculate(context.Background(), tripsTo(t))
+	if err == nil || !strings.Contains(err.Error(), "FAILURE") {
+		t.Errorf("got %v, want the status as an error", err)
+	}
+	requests = 0
+	outputs, err := fair.Calculate(context.Background(), flight.Trips{{ArrCode: "TXL"}})
+	if err != nil || len(outputs) != 0 || requests != 0 {
+		t.Errorf("got %v, %v after %d requests, want nothing asked for no flights", outputs, err, requests)
+	}
+}
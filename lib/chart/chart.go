@@ -0,0 +1,330 @@
+/*
+Package chart draws the study's figures as standalone SVG, so they can be
+remade from the command line rather than in a spreadsheet, and dropped
+into a web page as they are. Styling is inline, the charts look the same
+in a browser, an image viewer or a design tool.
+*/
+package chart
+
+import (
+	"fmt"
+	"html"
+	"io/ioutil"
+	"math"
+	"sort"
+	"strings"
+)
+
+const (
+	width  = 640
+	height = 360
+	top    = 40
+	right  = 24
+	bottom = 48
+	left   = 72
+	font   = `font-family="Helvetica, Arial, sans-serif" font-size="11"`
+)
+
+// Colours lines are drawn in, in turn.
+var palette = []string{"#e31c1c", "#222222", "#2b7bb9", "#f2a104", "#5aa02c"}
+
+// Series is one line of a Line chart, a value per label.
+type Series struct {
+	Name   string
+	Values []float64
+}
+
+type canvas struct {
+	b strings.Builder
+}
+
+func newCanvas(title string, w, h int) *canvas {
+	c := &canvas{}
+	fmt.Fprintf(&c.b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d" class="chart" role="img" %s>`, w, h, w, h, font)
+	fmt.Fprintf(&c.b, `<title>%s</title><rect width="%d" height="%d" fill="#ffffff"/>`, html.EscapeString(title), w, h)
+	fmt.Fprintf(&c.b, `<text x="%d" y="22" font-size="14" font-weight="bold">%s</text>`, left, html.EscapeString(title))
+	return c
+}
+
+func (c *canvas) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&c.b, format, args...)
+}
+
+func (c *canvas) text(x, y float64, anchor, s string) {
+	c.printf(`<text x="%.1f" y="%.1f" text-anchor="%s">%s</text>`, x, y, anchor, html.EscapeString(s))
+}
+
+func (c *canvas) String() string {
+	return c.b.String() + "</svg>\n"
+}
+
+// The y axis from zero to max, gridlines at round numbers, returning the
+// top of the scale it settled on.
+func (c *canvas) yAxis(max float64, label string) float64 {
+	step := niceStep(max, 5)
+	scaleMax := math.Ceil(max/step) * step
+	if scaleMax == 0 {
+		scaleMax = 1
+	}
+	for v := 0.0; v <= scaleMax+step/2; v += step {
+		y := plotY(v, scaleMax)
+		c.printf(`<line x1="%d" y1="%.1f" x2="%d" y2="%.1f" stroke="#dddddd"/>`, left, y, width-right, y)
+		c.text(left-6, y+4, "end", Number(v))
+	}
+	c.printf(`<text transform="translate(16 %d) rotate(-90)" text-anchor="middle">%s</text>`, top+(height-bottom-top)/2, html.EscapeString(label))
+	return scaleMax
+}
+
+func plotY(v, max float64) float64 {
+	return float64(height-bottom) - v/max*float64(height-bottom-top)
+}
+
+// niceStep picks a 1, 2 or 5 times a power of ten step giving about n ticks.
+func niceStep(max float64, n int) float64 {
+	if max <= 0 {
+		return 1
+	}
+	raw := max / float64(n)
+	mag := math.Pow(10, math.Floor(math.Log10(raw)))
+	for _, m := range []float64{1, 2, 5, 10} {
+		if raw <= m*mag {
+			return m * mag
+		}
+	}
+	return 10 * mag
+}
+
+// Number formats a value for a label, 1234567 as "1,234,567" and small
+// values to three significant figures.
+func Number(v float64) string {
+	if math.Abs(v) < 100 {
+		return fmt.Sprintf("%.3g", v)
+	}
+	s := fmt.Sprintf("%.0f", math.Abs(v))
+	var b strings.Builder
+	if v < 0 {
+		b.WriteByte('-')
+	}
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+/*
+Histogram counts the values into bins of equal width from zero to the
+largest, e.g. how many artists emitted 0-10 t, 10-20 t and so on.
+*/
+func Histogram(title string, values []float64, bins int, unit string) string {
+	counts, binWidth := bucket(values, bins)
+	bins = len(counts)
+
+	c := newCanvas(title, width, height)
+	var most float64
+	for _, n := range counts {
+		most = math.Max(most, n)
+	}
+	yMax := c.yAxis(most, "count")
+	slot := float64(width-left-right) / float64(bins)
+	every := labelEvery(bins, 8)
+	for i, n := range counts {
+		x := float64(left) + float64(i)*slot
+		y := plotY(n, yMax)
+		c.printf(`<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="#ffffff"><title>%s-%s %s: %s</title></rect>`,
+			x, y, slot, float64(height-bottom)-y, palette[0], Number(float64(i)*binWidth), Number(float64(i+1)*binWidth), html.EscapeString(unit), Number(n))
+		if i%every == 0 {
+			c.text(x, float64(height-bottom+16), "middle", Number(float64(i)*binWidth))
+		}
+	}
+	c.text(float64(width-right), float64(height-bottom+16), "end", Number(float64(bins)*binWidth))
+	c.text(float64(left+(width-left-right)/2), float64(height-8), "middle", unit)
+	return c.String()
+}
+
+// Counts the values into about bins bins of a round width, each holding the
+// values from its lower edge up to but not including the next, the largest
+// value in the last.
+func bucket(values []float64, bins int) ([]float64, float64) {
+	if bins < 1 {
+		bins = 1
+	}
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	binWidth := niceStep(max, bins)
+	bins = int(math.Ceil(max / binWidth))
+	if bins < 1 {
+		bins = 1
+	}
+	counts := make([]float64, bins)
+	for _, v := range values {
+		i := int(v / binWidth)
+		if i >= bins {
+			i = bins - 1
+		}
+		if i < 0 {
+			i = 0
+		}
+		counts[i]++
+	}
+	return counts, binWidth
+}
+
+// Bars ranks the labels by their values as horizontal bars, in the order
+// given, e.g. the top ten artists by CO2.
+func Bars(title string, labels []string, values []float64, unit string) string {
+	const (
+		bar      = 18
+		gap      = 6
+		barLeft  = 180
+		barRight = 90
+	)
+	h := top + len(values)*(bar+gap) + 24
+	c := newCanvas(title, width, h)
+	var max float64
+	for _, v := range values {
+		max = math.Max(max, v)
+	}
+	for i, v := range values {
+		y := top + i*(bar+gap)
+		w := 0.0
+		if max > 0 && v > 0 {
+			w = v / max * float64(width-barLeft-barRight)
+		}
+		label := ""
+		if i < len(labels) {
+			label = labels[i]
+		}
+		c.text(barLeft-8, float64(y+bar-5), "end", label)
+		c.printf(`<rect x="%d" y="%d" width="%.1f" height="%d" fill="%s"><title>%s: %s %s</title></rect>`,
+			barLeft, y, w, bar, palette[0], html.EscapeString(label), Number(v), html.EscapeString(unit))
+		c.text(float64(barLeft)+w+6, float64(y+bar-5), "start", Number(v))
+	}
+	c.text(float64(width-right), float64(h-8), "end", unit)
+	return c.String()
+}
+
+// Line draws each series across the labels, e.g. CO2 by month, with a legend
+// when there is more than one.
+func Line(title string, labels []string, series []Series, unit string) string {
+	c := newCanvas(title, width, height)
+	var max float64
+	for _, s := range series {
+		for _, v := range s.Values {
+			max = math.Max(max, v)
+		}
+	}
+	yMax := c.yAxis(max, unit)
+	x := func(i int) float64 {
+		if len(labels) < 2 {
+			return float64(left + (width-left-right)/2)
+		}
+		return float64(left) + float64(i)*float64(width-left-right)/float64(len(labels)-1)
+	}
+	every := labelEvery(len(labels), 12)
+	for i, label := range labels {
+		if i%every == 0 {
+			c.text(x(i), float64(height-bottom+16), "middle", label)
+		}
+	}
+	for n, s := range series {
+		colour := palette[n%len(palette)]
+		var points []string
+		for i, v := range s.Values {
+			if i >= len(labels) {
+				break
+			}
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x(i), plotY(v, yMax)))
+		}
+		c.printf(`<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), colour)
+		for i, v := range s.Values {
+			if i >= len(labels) {
+				break
+			}
+			c.printf(`<circle cx="%.1f" cy="%.1f" r="2.5" fill="%s"><title>%s %s: %s %s</title></circle>`,
+				x(i), plotY(v, yMax), colour, html.EscapeString(s.Name), html.EscapeString(labels[i]), Number(v), html.EscapeString(unit))
+		}
+		if len(series) > 1 {
+			lx := float64(width - right - 140)
+			ly := float64(top + 4 + n*16)
+			c.printf(`<rect x="%.1f" y="%.1f" width="10" height="10" fill="%s"/>`, lx, ly-9, colour)
+			c.text(lx+16, ly, "start", s.Name)
+		}
+	}
+	return c.String()
+}
+
+/*
+Lorenz plots the cumulative share of the total held by the smallest values,
+e.g. how much of the CO2 the lowest emitting half of the artists account
+for, against the line of equal shares. The Gini coefficient is given in
+the title.
+*/
+func Lorenz(title string, values []float64) string {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	var total float64
+	for _, v := range sorted {
+		total += v
+	}
+	c := newCanvas(fmt.Sprintf("%s (Gini %.2f)", title, Gini(values)), width, height)
+	size := float64(height - top - bottom)
+	px := func(share float64) float64 { return float64(left) + share*size }
+	py := func(share float64) float64 { return float64(height-bottom) - share*size }
+	for _, share := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		c.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#dddddd"/>`, px(0), py(share), px(1), py(share))
+		c.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#dddddd"/>`, px(share), py(0), px(share), py(1))
+		c.text(px(0)-6, py(share)+4, "end", fmt.Sprintf("%.0f%%", share*100))
+		c.text(px(share), py(0)+16, "middle", fmt.Sprintf("%.0f%%", share*100))
+	}
+	c.printf(`<line x1="%.1f" y1="%.1f" x2="%.1f" y2="%.1f" stroke="#222222" stroke-dasharray="4 4"/>`, px(0), py(0), px(1), py(1))
+	points := []string{fmt.Sprintf("%.1f,%.1f", px(0), py(0))}
+	var running float64
+	for i, v := range sorted {
+		running += v
+		share := 0.0
+		if total > 0 {
+			share = running / total
+		}
+		points = append(points, fmt.Sprintf("%.1f,%.1f", px(float64(i+1)/float64(len(sorted))), py(share)))
+	}
+	c.printf(`<polyline points="%s" fill="none" stroke="%s" stroke-width="2"/>`, strings.Join(points, " "), palette[0])
+	c.text(px(0.5), float64(height-8), "middle", "share of artists, lowest first")
+	c.printf(`<text transform="translate(%.1f %.1f) rotate(-90)" text-anchor="middle">share of total</text>`, px(0)-44, py(0.5))
+	c.text(px(1)+16, py(1)+4, "start", "equal shares")
+	return c.String()
+}
+
+// Gini is 0 when every value is the same and approaches 1 when one holds
+// the whole total.
+func Gini(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	var total, weighted float64
+	for i, v := range sorted {
+		total += v
+		weighted += float64(i+1) * v
+	}
+	n := float64(len(sorted))
+	if n == 0 || total == 0 {
+		return 0
+	}
+	return 2*weighted/(n*total) - (n+1)/n
+}
+
+// Write saves a chart as an .svg file.
+func Write(fName, svg string) error {
+	return ioutil.WriteFile(fName, []byte(svg), 0644)
+}
+
+// Labels every nth category so no more than max are shown.
+func labelEvery(n, max int) int {
+	if n <= max {
+		return 1
+	}
+	return int(math.Ceil(float64(n) / float64(max)))
+}
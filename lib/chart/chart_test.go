@@ -0,0 +1,70 @@
+package chart
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestGini(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		want   float64
+	}{
+		{"none", nil, 0},
+		{"all zero", []float64{0, 0, 0}, 0},
+		{"all equal", []float64{5, 5, 5, 5}, 0},
+		{"one of two holds everything", []float64{0, 10}, 0.5},
+		{"one of four holds everything", []float64{0, 0, 7, 0}, 0.75},
+		{"one of ten holds everything", []float64{0, 0, 0, 0, 0, 0, 0, 0, 0, 1}, 0.9},
+		{"order does not matter", []float64{3, 1, 2}, 2.0 / 9},
+	}
+	for _, tt := range tests {
+		if got := Gini(tt.values); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Gini = %f, want %f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBucket(t *testing.T) {
+	tests := []struct {
+		name   string
+		values []float64
+		bins   int
+		width  float64
+		counts string
+	}{
+		// Widths of 20, each bin from its lower edge up to the next
+		{"edges", []float64{0, 10, 19.99, 20, 39.99, 40, 100}, 5, 20, "[3 2 1 0 1]"},
+		{"largest in the last bin", []float64{1, 2, 3, 4, 5}, 5, 1, "[0 1 1 1 2]"},
+		{"rounded width", []float64{0, 7, 14}, 3, 5, "[1 1 1]"},
+		{"negative values in the first", []float64{-3, 50}, 5, 10, "[1 0 0 0 1]"},
+		{"all zero", []float64{0, 0}, 5, 1, "[2]"},
+		{"no bins asked for", []float64{1, 9}, 0, 10, "[2]"},
+	}
+	for _, tt := range tests {
+		counts, width := bucket(tt.values, tt.bins)
+		if width != tt.width || fmt.Sprint(counts) != tt.counts {
+			t.Errorf("%s: got %v of %g, want %s of %g", tt.name, counts, width, tt.counts, tt.width)
+		}
+	}
+}
+
+func TestHistogram(t *testing.T) {
+	svg := Histogram("CO2 per artist", []float64{0, 10, 19.99, 20, 100}, 5, "t")
+	for _, want := range []string{"<title>0-20 t: 3</title>", "<title>20-40 t: 1</title>", "<title>80-100 t: 1</title>"} {
+		if !strings.Contains(svg, want) {
+			t.Errorf("histogram has no %s", want)
+		}
+	}
+}
+
+func TestNumber(t *testing.T) {
+	for v, want := range map[float64]string{0: "0", 12.345: "12.3", 999: "999", 1234567: "1,234,567", -4500: "-4,500"} {
+		if got := Number(v); got != want {
+			t.Errorf("Number(%g) = %q, want %q", v, got, want)
+		}
+	}
+}
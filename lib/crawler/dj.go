@@ -1,24 +1,31 @@
 package crawler
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/throttle"
+	"github.com/cleanscene.flights/lib/venue"
 )
 
 type Crawler interface {
 	GetArtistUrl(string) (string, error)
-	GetArtistEvents(string, string) (Events, error)
+	ArtistUrlFromSlug(string) string
+	GetArtistEvents(context.Context, string, string) (Events, error)
 }
 
-func New(url string) (Crawler, error) {
-	resp, err := http.Get(fmt.Sprintf("%s/dj.aspx", url))
+// New crawls RA at url through client, nil for the default client. Clubs
+// already in the venue registry are not fetched again, venues may be nil to
+// always fetch them.
+func New(ctx context.Context, url string, venues venue.Registry, client throttle.Client) (Crawler, error) {
+	resp, err := throttle.Get(ctx, client, fmt.Sprintf("%s/dj.aspx", url))
 	if err != nil {
 		return djCrawler{}, err
 	}
@@ -28,7 +35,7 @@ func New(url string) (Crawler, error) {
 	if resp.StatusCode == http.StatusOK {
 		document, err := goquery.NewDocumentFromReader(resp.Body)
 		if err != nil {
-			log.Fatal("Error loading HTTP response body. ", err)
+			return djCrawler{}, fmt.Errorf("reading the RA artist list: %v", err)
 		}
 		// Find all links
 		document.Find("a").Each(func(index int, element *goquery.Selection) {
@@ -45,6 +52,8 @@ func New(url string) (Crawler, error) {
 	return djCrawler{
 		baseUrl:     url,
 		artistLinks: artistUrls,
+		venues:      venues,
+		client:      client,
 	}, nil
 
 }
@@ -52,6 +61,8 @@ func New(url string) (Crawler, error) {
 type djCrawler struct {
 	baseUrl     string
 	artistLinks map[string]string
+	venues      venue.Registry
+	client      throttle.Client
 }
 
 func (c djCrawler) GetArtistUrl(name string) (string, error) {
@@ -63,25 +74,29 @@ func (c djCrawler) GetArtistUrl(name string) (string, error) {
 
 }
 
-var scrapeClubFunc = func(location string, document *goquery.Document) func(i int, s *goquery.Selection) {
+func (c djCrawler) ArtistUrlFromSlug(slug string) string {
+	return fmt.Sprintf("%s/dj/%s", c.baseUrl, slug)
+}
+
+var scrapeClubFunc = func(location *string, document *goquery.Document) func(i int, s *goquery.Selection) {
 	return func(i int, s *goquery.Selection) {
 		href, _ := s.Attr("href")
 		if s.Text() == "Google Maps" && strings.Contains(href, "maps") {
-			location = href
+			*location = href
 			return
 		} else {
 			document.Find("span").Each(func(i int, s *goquery.Selection) {
 				ip, _ := s.Attr("itemprop")
 				if ip == "street-address" {
-					location = s.Text()
+					*location = s.Text()
 				}
 			})
 		}
 	}
 }
 
-func (c djCrawler) findClubLocation(clubLink string) (string, error) {
-	resp, err := http.Get(c.baseUrl + clubLink)
+func (c djCrawler) findClubLocation(ctx context.Context, clubLink string) (string, error) {
+	resp, err := throttle.Get(ctx, c.client, c.baseUrl+clubLink)
 	if err != nil {
 		return "", err
 	}
@@ -91,7 +106,7 @@ func (c djCrawler) findClubLocation(clubLink string) (string, error) {
 		return "", err
 	}
 	var location string
-	document.Find("a").Each(scrapeClubFunc(location, document))
+	document.Find("a").Each(scrapeClubFunc(&location, document))
 	return location, nil
 }
 
@@ -104,9 +119,37 @@ func parseEventText(es string) (time.Time, string) {
 	return date, title
 }
 
-type Events map[time.Time]event.Event
+// Events are an artist's gigs by date, a day holding more than one when the
+// artist plays twice, e.g. a festival slot and a club night.
+type Events map[time.Time][]event.Event
+
+// Add lists a gig on its date, after any already listed that day.
+func (es Events) Add(date time.Time, e event.Event) {
+	es[date] = append(es[date], e)
+}
+
+// Dates are the days with gigs, in order.
+func (es Events) Dates() []time.Time {
+	var dates []time.Time
+	for d := range es {
+		dates = append(dates, d)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return dates
+}
+
+// Count is the number of gigs, not of days.
+func (es Events) Count() int {
+	n := 0
+	for _, day := range es {
+		n += len(day)
+	}
+	return n
+}
 
-var eventScrapeFunc = func(events Events, c djCrawler) func(i int, s *goquery.Selection) {
+var eventScrapeFunc = func(ctx context.Context, events Events, c djCrawler) func(i int, s *goquery.Selection) {
 	return func(i int, s *goquery.Selection) {
 		ip, _ := s.Attr("class")
 		if ip == "event" {
@@ -114,14 +157,7 @@ var eventScrapeFunc = func(events Events, c djCrawler) func(i int, s *goquery.Se
 			s.Find("a").Each(func(i int, element *goquery.Selection) {
 				href, _ := element.Attr("href")
 				if strings.Contains(href, "/club") {
-					location, err := c.findClubLocation(href)
-					if err != nil {
-						err = err
-					}
-					events[date] = event.Event{
-						Title:    title,
-						Location: location,
-					}
+					events.Add(date, c.clubEvent(ctx, title, element.Text(), href))
 				}
 
 			})
@@ -130,9 +166,33 @@ var eventScrapeFunc = func(events Events, c djCrawler) func(i int, s *goquery.Se
 	}
 }
 
-func (c djCrawler) GetArtistEvents(artistUrl, tourYear string) (Events, error) {
+// Fills in the club from the venue registry, fetching its page only the
+// first time it is seen.
+func (c djCrawler) clubEvent(ctx context.Context, title, club, href string) event.Event {
+	e := event.Event{Title: title, Venue: club, VenueID: venue.ClubID(href)}
+	if c.venues != nil {
+		if v, ok := c.venues.Get(e.VenueID); ok && (v.Address != "" || v.HasCoords()) {
+			e.Location, e.Lat, e.Lng = v.Address, v.Lat, v.Lng
+			if v.Name != "" {
+				e.Venue = v.Name
+			}
+			return e
+		}
+	}
+	location, err := c.findClubLocation(ctx, href)
+	if err != nil {
+		fmt.Println(err.Error())
+	}
+	e.Location = location
+	if c.venues != nil && location != "" {
+		c.venues.Put(venue.Venue{ID: e.VenueID, Name: club, Address: location, Source: venue.RA})
+	}
+	return e
+}
+
+func (c djCrawler) GetArtistEvents(ctx context.Context, artistUrl, tourYear string) (Events, error) {
 	events := make(Events)
-	resp, err := http.Get(artistUrl + "/dates?yr=" + tourYear)
+	resp, err := throttle.Get(ctx, c.client, artistUrl+"/dates?yr="+tourYear)
 	if err != nil {
 		return events, err
 	}
@@ -140,10 +200,10 @@ func (c djCrawler) GetArtistEvents(artistUrl, tourYear string) (Events, error) {
 	if resp.StatusCode == http.StatusOK {
 		document, err := goquery.NewDocumentFromReader(resp.Body)
 		if err != nil {
-			log.Fatal("Error loading HTTP response body. ", err)
+			return events, fmt.Errorf("reading the dates of %s: %v", artistUrl, err)
 		}
-		document.Find("article").Each(eventScrapeFunc(events, c))
+		document.Find("article").Each(eventScrapeFunc(ctx, events, c))
 
 	}
-	return events, err
+	return events, nil
 }
@@ -0,0 +1,27 @@
+package crawler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// RA cutting a page off is an error for the artist, not the end of the run.
+func TestTruncatedPage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "4096")
+		w.Write([]byte("<html><body><article>"))
+	}))
+	defer srv.Close()
+
+	if _, err := New(context.Background(), srv.URL, nil, nil); err == nil {
+		t.Error("New read a truncated artist list")
+	}
+	c := djCrawler{baseUrl: srv.URL}
+	_, err := c.GetArtistEvents(context.Background(), srv.URL+"/dj/tiga", "2019")
+	if err == nil || !strings.Contains(err.Error(), "/dj/tiga") {
+		t.Errorf("got %v, want an error naming the artist", err)
+	}
+}
@@ -6,11 +6,41 @@ import (
 )
 
 type Event struct {
-	Title    string
+	Title string
+	// Name of the club or festival site, when the source gives it.
+	Venue    string
 	Location string
-	City     string
-	Country  string
-	AirCode  string
+	// RA club ID of the venue, for looking it up in the venue registry.
+	VenueID     string
+	City        string
+	Country     string
+	CountryCode string
+	AirCode     string
+	// Venue coordinates, when the source gives them.
+	Lat float64
+	Lng float64
+	// IANA time zone of the venue, e.g. "Europe/Berlin".
+	TimeZone string
+	// Set when the source gives set times, otherwise the planner assumes them.
+	Start time.Time
+	End   time.Time
+	// Event source the gig was listed by, and anything needing a human to review.
+	Source string
+	Flags  []string
+	// Why the gig could not be placed at an airport, e.g. a failed geocode.
+	Failure string
+}
+
+// Zone returns the venue's time zone, falling back to UTC when it is unknown.
+func (e Event) Zone() *time.Location {
+	if e.TimeZone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(e.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 func ParseDate(eventStr string, startIdx int) (time.Time, int) {
@@ -8,7 +8,7 @@ import (
 
 	"github.com/cleanscene.flights/lib/event"
 	"github.com/cleanscene.flights/lib/ra"
-	country_mapper "github.com/pirsquare/country-mapper"
+	"github.com/cleanscene.flights/lib/region"
 )
 
 type Trips []Trip
@@ -16,21 +16,52 @@ type Trips []Trip
 type Trip struct {
 	DepCode string
 	ArrCode string
-	Date    string
+	// Departure time, in the time zone of the departure airport when known
+	// and UTC otherwise.
+	Date time.Time
+	// Hub of an inferred one-stop itinerary, set on both of its segments.
+	Via string
+	// Label of the tour or residency the trip is part of, if any.
+	Tour string
+	// Members of the act and their crew on the flight.
+	Passengers int
+	// Gigs the trip leaves from and flies to, zero for home or a base.
+	From Gig
+	To   Gig
+}
+
+// Gig identifies the event a trip is attributed to.
+type Gig struct {
+	Date  time.Time
+	Title string
+	Venue string
+}
+
+func (g Gig) IsZero() bool { return g.Date.IsZero() }
+
+func gigOf(date time.Time, e event.Event) Gig {
+	venue := e.Venue
+	if venue == "" {
+		venue = e.Location
+	}
+	return Gig{Date: date, Title: e.Title, Venue: venue}
 }
 
 type Planner interface {
 	Plan(ra.Artist) (Trips, error)
+	Tours(ra.Artist) []Tour
 }
 
-func NewPlanner(countryClient *country_mapper.CountryInfoClient) Planner {
+func NewPlanner(regions region.Regions, schedule Schedule) Planner {
 	return FlightPlanner{
-		cc: countryClient,
+		regions:  regions,
+		schedule: schedule,
 	}
 }
 
 type FlightPlanner struct {
-	cc *country_mapper.CountryInfoClient
+	regions  region.Regions
+	schedule Schedule
 }
 
 /*
@@ -41,62 +72,120 @@ An artist will fly from one gig to the next iff:
 1. If the gigs are within two days of eachother
 2. If the gigs outside the home base continent, on the same foreign continent, within two weeks of eachother
 
-Otherwise we assume the artist returns home in between gigs.
+Otherwise we assume the artist returns home in between gigs, or to the
+residency they are playing at the time (see cluster).
+
+Travel dates follow the planner's Schedule: the artist leaves early enough
+to land the arrival buffer before the gig starts, and flies on the morning
+after the gig.
 
 */
 
+// How close together gigs are flown between directly, anywhere and on the
+// same foreign continent.
+const (
+	ConnectWithin        = 2 * 24 * time.Hour
+	ForeignConnectWithin = 14 * 24 * time.Hour
+)
+
 func withinTwoDays(d1, d2 time.Time) bool {
-	days := d2.Sub(d1).Hours() / 24
-	return days <= 2
+	return d2.Sub(d1) <= ConnectWithin
 }
 
 func withinTwoWeeks(d1, d2 time.Time) bool {
-	days := d2.Sub(d1).Hours() / 24
-	return days <= 14
+	return d2.Sub(d1) <= ForeignConnectWithin
 }
 
-func sortByDate(events map[time.Time]event.Event) []time.Time {
+// A gig and the day it is listed on.
+type datedGig struct {
+	date  time.Time
+	event event.Event
+}
 
-	var keys []time.Time
-	for k, _ := range events {
-		keys = append(keys, k)
+// The artist's gigs by date, those on the same day by their set times when
+// both have them and as listed otherwise.
+func gigsInOrder(events ra.Events) []datedGig {
+	var gigs []datedGig
+	for _, d := range events.Dates() {
+		day := append([]event.Event(nil), events[d]...)
+		sort.SliceStable(day, func(i, j int) bool {
+			s1, s2 := day[i].Start, day[j].Start
+			return !s1.IsZero() && !s2.IsZero() && s1.Before(s2)
+		})
+		for _, e := range day {
+			gigs = append(gigs, datedGig{date: d, event: e})
+		}
 	}
-	sort.Slice(keys, func(i, j int) bool {
-		return keys[i].Before(keys[j])
-	})
-	return keys
+	return gigs
 }
 
 func makeTrip(depCity, arrCity string, date time.Time) Trip {
 	return Trip{
 		DepCode: depCity,
 		ArrCode: arrCity,
-		Date:    date.String()[0:10],
+		Date:    date,
 	}
 }
 
-func (p FlightPlanner) sameForeignContinent(c1, c2, home string) bool {
-	gigsSame := p.cc.MapByName(c1).Region == p.cc.MapByName(c1).Region
-	homeData := p.cc.MapByName(home)
-	c1Data := p.cc.MapByName(c1)
-	if homeData != nil && c1Data != nil {
-		homeDiff := p.cc.MapByName(home).Region != p.cc.MapByName(c1).Region
-		return gigsSame && homeDiff
+// Prefer the ISO code from the airport lookup, venue country names vary by source.
+func countryOf(e event.Event) string {
+	if e.CountryCode != "" {
+		return e.CountryCode
 	}
-	return false
+	return e.Country
+}
+
+// The time zone of an airport the artist played at, UTC for their home or a
+// base where they never played.
+func zoneAt(a ra.Artist, code string) *time.Location {
+	for _, day := range a.Events {
+		for _, e := range day {
+			if e.AirCode == code && e.TimeZone != "" {
+				return e.Zone()
+			}
+		}
+	}
+	return time.UTC
+}
+
+// The country of an airport the artist played at.
+func countryAt(a ra.Artist, code string) string {
+	for _, day := range a.Events {
+		for _, e := range day {
+			if e.AirCode == code {
+				return countryOf(e)
+			}
+		}
+	}
+	return ""
 }
 
 func (p FlightPlanner) shouldFlyHome(e1, e2 event.Event, d1, d2 time.Time, homeCountry string) bool {
 	if withinTwoDays(d1, d2) {
 		return false
 	}
-	if withinTwoWeeks(d1, d2) && p.sameForeignContinent(e1.Country, e2.Country, homeCountry) {
+	if withinTwoWeeks(d1, d2) && p.regions.SameForeignContinent(countryOf(e1), countryOf(e2), homeCountry) {
 		return false
 	}
 	return true
 
 }
 
+func (p FlightPlanner) Tours(a ra.Artist) []Tour {
+	return cluster(a, gigsInOrder(a.Events), p.regions, p.schedule)
+}
+
+// Gigs played during a residency count towards it, even at other venues.
+func labelFor(tours []Tour, d time.Time) string {
+	if t, ok := tourOf(tours, d); ok {
+		return t.Label
+	}
+	if t, ok := residencyBetween(tours, d, d); ok {
+		return t.Label
+	}
+	return ""
+}
+
 func (p FlightPlanner) Plan(a ra.Artist) (Trips, error) {
 	var trips = make(Trips, 0)
 	if a.AirCode == "" {
@@ -104,33 +193,63 @@ func (p FlightPlanner) Plan(a ra.Artist) (Trips, error) {
 	}
 	fmt.Printf("Creating flight plan..\n")
 	homeCity, currCity := a.AirCode, a.AirCode
-	events := sortByDate(a.Events)
+	currCountry := a.Country
+	// When travelling on from a gig, the artist cannot leave before it is over.
+	var (
+		earliest time.Time
+		lastGig  Gig
+	)
+	gigs := gigsInOrder(a.Events)
+	tours := cluster(a, gigs, p.regions, p.schedule)
+
+	for index, g := range gigs {
+		date, event := g.date, g.event
+		start, end := p.schedule.gigTimes(event, date)
 
-	for index, date := range events {
-		if index+1 == len(events) {
-			trip := makeTrip(currCity, homeCity, date)
-			trips = append(trips, trip)
-			return trips, nil
-		}
-		event := a.Events[date]
 		// Create a trip from the current city to the event we are looking at
-		trip := makeTrip(currCity, event.AirCode, date)
+		depart := p.schedule.departFor(start, haul(p.regions, currCountry, countryOf(event)))
+		if depart.Before(earliest) {
+			depart = earliest
+		}
+		// Gig times are in the venue's zone, the trip leaves in its own.
+		depart = depart.In(zoneAt(a, currCity))
+		label := labelFor(tours, date)
+		trip := makeTrip(currCity, event.AirCode, depart)
+		trip.Tour = label
+		trip.Passengers = a.Passengers()
+		trip.From, trip.To = lastGig, gigOf(date, event)
 		if trip.DepCode != trip.ArrCode {
 			trips = append(trips, trip)
 		}
-		currCity = event.AirCode
+		currCity, currCountry = event.AirCode, countryOf(event)
+		earliest = p.schedule.departAfter(end)
+		lastGig = trip.To
 
 		// Check the next event to see if we should then fly home
-		nextEvent := a.Events[events[index+1]]
-		if p.shouldFlyHome(event, nextEvent, date, events[index+1], a.Country) {
-			homeTrip := makeTrip(currCity, homeCity, events[index+1])
-			currCity = homeCity
-			// Avoid tacking on a home trip from home
-			if homeTrip.DepCode != homeTrip.ArrCode {
-				trips = append(trips, homeTrip)
+		baseCity, baseCountry := homeCity, a.Country
+		if index+1 < len(gigs) {
+			next := gigs[index+1]
+			if !p.shouldFlyHome(event, next.event, date, next.date, a.Country) {
+				continue
+			}
+			if t, ok := tourOf(tours, date); ok && t.Kind == TourRun && t.contains(next.date) {
+				continue
+			}
+			if r, ok := residencyBetween(tours, date, next.date); ok {
+				baseCity, baseCountry = r.Base, countryAt(a, r.Base)
 			}
 		}
-
+		homeTrip := makeTrip(currCity, baseCity, earliest)
+		homeTrip.Tour = label
+		homeTrip.Passengers = a.Passengers()
+		homeTrip.From = lastGig
+		currCity, currCountry = baseCity, baseCountry
+		earliest = time.Time{}
+		lastGig = Gig{}
+		// Avoid tacking on a home trip from home
+		if homeTrip.DepCode != homeTrip.ArrCode {
+			trips = append(trips, homeTrip)
+		}
 	}
 	return trips, nil
 
@@ -0,0 +1,207 @@
+package flight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/region"
+)
+
+func zone(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("no time zone data for %s: %v", name, err)
+	}
+	return loc
+}
+
+func gig(code, country, tz string) event.Event {
+	return event.Event{Title: code + " night", Venue: code + " club", AirCode: code, CountryCode: country, TimeZone: tz}
+}
+
+func day(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+/*
+The outbound leg of each case leaves so as to land the haul's arrival buffer
+before a 23:00 local start, and is dated in the zone of the airport it
+leaves from: the artist's home zone when they play at home too, UTC
+otherwise.
+*/
+func TestPlanDepartureDates(t *testing.T) {
+	tests := []struct {
+		name     string
+		home     string
+		country  string
+		homeGig  event.Event
+		gig      event.Event
+		date     string
+		wantZone string
+		want     string
+	}{
+		{
+			name: "short haul", home: "TXL", country: "DE",
+			homeGig: gig("TXL", "DE", "Europe/Berlin"),
+			gig:     gig("AMS", "NL", "Europe/Amsterdam"), date: "2019-03-20",
+			// 23:00 less 8h in Amsterdam, the same time in Berlin.
+			wantZone: "Europe/Berlin", want: "2019-03-20 15:00",
+		},
+		{
+			name: "medium haul", home: "TXL", country: "DE",
+			homeGig: gig("TXL", "DE", "Europe/Berlin"),
+			gig:     gig("LIS", "PT", "Europe/Lisbon"), date: "2019-03-20",
+			// 23:00 less 12h in Lisbon is 11:00, 12:00 in Berlin.
+			wantZone: "Europe/Berlin", want: "2019-03-20 12:00",
+		},
+		{
+			name: "long haul", home: "TXL", country: "DE",
+			homeGig: gig("TXL", "DE", "Europe/Berlin"),
+			gig:     gig("JFK", "US", "America/New_York"), date: "2019-03-20",
+			// 23:00 less 30h in New York is 17:00 the day before, 22:00 in Berlin.
+			wantZone: "Europe/Berlin", want: "2019-03-19 22:00",
+		},
+		{
+			name: "long haul east across the date line", home: "AKL", country: "NZ",
+			homeGig: gig("AKL", "NZ", "Pacific/Auckland"),
+			gig:     gig("HNL", "US", "Pacific/Honolulu"), date: "2019-03-10",
+			// 17:00 on the 9th in Honolulu is already 16:00 on the 10th in Auckland.
+			wantZone: "Pacific/Auckland", want: "2019-03-10 16:00",
+		},
+		{
+			name: "long haul west across the date line", home: "HNL", country: "US",
+			homeGig: gig("HNL", "US", "Pacific/Honolulu"),
+			gig:     gig("AKL", "NZ", "Pacific/Auckland"), date: "2019-03-10",
+			// 17:00 on the 9th in Auckland is 18:00 on the 8th in Honolulu.
+			wantZone: "Pacific/Honolulu", want: "2019-03-08 18:00",
+		},
+		{
+			name: "home zone unknown", home: "TXL", country: "DE",
+			gig: gig("JFK", "US", "America/New_York"), date: "2019-03-20",
+			wantZone: "UTC", want: "2019-03-19 21:00",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, err := time.ParseInLocation("2006-01-02 15:04", tt.want, zone(t, tt.wantZone))
+			if err != nil {
+				t.Fatal(err)
+			}
+			zone(t, tt.gig.TimeZone)
+			a := ra.Artist{Name: "A", AirCode: tt.home, Country: tt.country, Events: ra.Events{}}
+			if tt.homeGig.AirCode != "" {
+				a.Events.Add(day("2019-03-01"), tt.homeGig)
+			}
+			a.Events.Add(day(tt.date), tt.gig)
+
+			trips, err := NewPlanner(region.New(), DefaultSchedule()).Plan(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(trips) != 2 {
+				t.Fatalf("got %d trips, want there and back: %+v", len(trips), trips)
+			}
+			out := trips[0]
+			if out.DepCode != tt.home || out.ArrCode != tt.gig.AirCode {
+				t.Fatalf("outbound %s-%s, want %s-%s", out.DepCode, out.ArrCode, tt.home, tt.gig.AirCode)
+			}
+			if !out.Date.Equal(want) || out.Date.Location().String() != tt.wantZone {
+				t.Errorf("outbound leaves %s, want %s", out.Date.Format("2006-01-02 15:04 MST"), want.Format("2006-01-02 15:04 MST"))
+			}
+			// The flight home leaves the morning after the gig, in the gig's zone.
+			back := trips[1]
+			if back.Date.Location().String() != tt.gig.TimeZone {
+				t.Errorf("return leaves in %s, want %s", back.Date.Location(), tt.gig.TimeZone)
+			}
+		})
+	}
+}
+
+// Gigs a few days apart on the same foreign continent are flown between,
+// on the artist's own continent the artist goes home in between.
+func TestPlanForeignContinent(t *testing.T) {
+	tests := []struct {
+		name    string
+		country string
+		gigs    []event.Event
+		want    []string
+	}{
+		{
+			name: "european in north america", country: "DE",
+			gigs: []event.Event{gig("JFK", "US", ""), gig("SJU", "PR", "")},
+			want: []string{"TXL-JFK", "JFK-SJU", "SJU-TXL"},
+		},
+		{
+			name: "european at home", country: "DE",
+			gigs: []event.Event{gig("LIS", "PT", ""), gig("SVO", "RU", "")},
+			want: []string{"TXL-LIS", "LIS-TXL", "TXL-SVO", "SVO-TXL"},
+		},
+		{
+			name: "istanbul is asia", country: "DE",
+			gigs: []event.Event{gig("IST", "TR", ""), gig("NRT", "JP", "")},
+			want: []string{"TXL-IST", "IST-NRT", "NRT-TXL"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := ra.Artist{Name: "A", AirCode: "TXL", Country: tt.country, Events: ra.Events{}}
+			for i, e := range tt.gigs {
+				a.Events.Add(day("2019-06-01").AddDate(0, 0, 5*i), e)
+			}
+			trips, err := NewPlanner(region.New(), DefaultSchedule()).Plan(a)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, trip := range trips {
+				got = append(got, trip.DepCode+"-"+trip.ArrCode)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// Two gigs on one day are both flown to, in the order of their set times.
+func TestPlanDoubleHeader(t *testing.T) {
+	berlin := zone(t, "Europe/Berlin")
+	festival := gig("HAM", "DE", "Europe/Berlin")
+	festival.Start = time.Date(2019, 7, 6, 16, 0, 0, 0, berlin)
+	festival.End = time.Date(2019, 7, 6, 18, 0, 0, 0, berlin)
+	club := gig("MUC", "DE", "Europe/Berlin")
+	club.Start = time.Date(2019, 7, 6, 23, 30, 0, 0, berlin)
+
+	a := ra.Artist{Name: "A", AirCode: "TXL", Country: "DE", Events: ra.Events{}}
+	// Listed the other way round
+	a.Events.Add(day("2019-07-06"), club)
+	a.Events.Add(day("2019-07-06"), festival)
+	if a.Events.Count() != 2 {
+		t.Fatalf("got %d gigs, want both", a.Events.Count())
+	}
+
+	trips, err := NewPlanner(region.New(), DefaultSchedule()).Plan(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, trip := range trips {
+		got = append(got, trip.DepCode+"-"+trip.ArrCode)
+	}
+	want := []string{"TXL-HAM", "HAM-MUC", "MUC-TXL"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if trips[1].From.Venue != festival.Venue || trips[1].To.Venue != club.Venue {
+		t.Errorf("HAM-MUC attributed from %q to %q", trips[1].From.Venue, trips[1].To.Venue)
+	}
+}
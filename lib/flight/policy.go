@@ -0,0 +1,39 @@
+package flight
+
+import (
+	"fmt"
+	"time"
+)
+
+// Rules spells out the planner's assumptions with the schedule's values, so
+// what is published next to the results is what produced them.
+func (s Schedule) Rules() []string {
+	return []string{
+		fmt.Sprintf("An artist flies straight from one gig to the next when they are at most %s apart.", span(ConnectWithin)),
+		fmt.Sprintf("Gigs on the same continent, away from the artist's home continent and at most %s apart, are flown between directly too.", span(ForeignConnectWithin)),
+		"Otherwise the artist flies home in between, or back to the residency they are playing at the time.",
+		fmt.Sprintf("Without set times, a gig starts %s after local midnight and lasts %s.", span(s.DefaultStart), span(s.DefaultLength)),
+		fmt.Sprintf("The artist lands %s before a short-haul gig (in the same UN subregion), %s before a medium-haul gig (on the same continent) and %s before a long-haul one.",
+			span(s.ArrivalBuffer[ShortHaul]), span(s.ArrivalBuffer[MediumHaul]), span(s.ArrivalBuffer[LongHaul])),
+		fmt.Sprintf("They fly on %s after the gig ends.", span(s.DepartureDelay)),
+		fmt.Sprintf("At least %d gigs at the same airport away from home, no more than %s apart, make a residency, flown to once.", s.MinResidencyGigs, span(s.ResidencyGap)),
+		fmt.Sprintf("At least %d other gigs in a row on the same foreign continent, no more than %s apart, make a tour.", s.MinTourGigs, span(s.TourGap)),
+	}
+}
+
+// Whole days or hours, e.g. "2 days" or "30 hours".
+func span(d time.Duration) string {
+	plural := func(n int64, unit string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	if d >= 24*time.Hour && d%(24*time.Hour) == 0 {
+		return plural(int64(d/(24*time.Hour)), "day")
+	}
+	if d%time.Hour == 0 {
+		return plural(int64(d/time.Hour), "hour")
+	}
+	return d.String()
+}
@@ -0,0 +1,133 @@
+package flight
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+type Routes interface {
+	Direct(string, string) bool
+	Connection(string, string) (string, bool)
+	Connect(Trips) Trips
+}
+
+// Carriers flying each route without stops, by departure then arrival airport.
+type routeTable map[string]map[string]int
+
+/*
+LoadRoutes reads an OpenFlights routes.dat file:
+
+	airline,airline id,source,source id,destination,destination id,codeshare,stops,equipment
+
+Only routes with zero stops count as direct service.
+*/
+func LoadRoutes(fname string) (Routes, error) {
+	var routes = make(routeTable)
+	file, err := os.Open(fname)
+	if err != nil {
+		return routes, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	line := 0
+	for {
+		arr, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return routes, fmt.Errorf("%s:%d: %v", fname, line, err)
+		}
+		if len(arr) < 9 {
+			return routes, fmt.Errorf("%s:%d: expected 9 fields, got %d", fname, line, len(arr))
+		}
+		src, dst, stops := arr[2], arr[4], arr[7]
+		if stops != "0" || len(src) != 3 || len(dst) != 3 {
+			continue
+		}
+		if routes[src] == nil {
+			routes[src] = make(map[string]int)
+		}
+		routes[src][dst]++
+	}
+	return routes, nil
+}
+
+func (r routeTable) Direct(dep, arr string) bool {
+	return r[dep][arr] > 0
+}
+
+/*
+Connection picks the most plausible hub for a one-stop itinerary: the one
+where the less served of the two legs has the most carriers, then the
+better connected hub. Returns false when no one-stop itinerary exists.
+*/
+func (r routeTable) Connection(dep, arr string) (string, bool) {
+	var hubs []string
+	for hub := range r[dep] {
+		if hub != arr && r[hub][arr] > 0 {
+			hubs = append(hubs, hub)
+		}
+	}
+	if len(hubs) == 0 {
+		return "", false
+	}
+	sort.Slice(hubs, func(i, j int) bool {
+		ci, cj := r.carriers(dep, hubs[i], arr), r.carriers(dep, hubs[j], arr)
+		if ci != cj {
+			return ci > cj
+		}
+		if len(r[hubs[i]]) != len(r[hubs[j]]) {
+			return len(r[hubs[i]]) > len(r[hubs[j]])
+		}
+		return hubs[i] < hubs[j]
+	})
+	return hubs[0], true
+}
+
+func (r routeTable) carriers(dep, hub, arr string) int {
+	in, out := r[dep][hub], r[hub][arr]
+	if in < out {
+		return in
+	}
+	return out
+}
+
+/*
+The second segment of a one-stop itinerary is taken to leave this long after
+the first, its flight time plus the layover. The routes table has no flight
+times, so it is the same for every hub, enough to move a connection made
+overnight onto the next day.
+*/
+const HubConnection = 6 * time.Hour
+
+// Connect splits every trip without direct service into its two segments so
+// emissions are calculated for each flight actually taken.
+func (r routeTable) Connect(trips Trips) Trips {
+	var connected = make(Trips, 0, len(trips))
+	for _, trip := range trips {
+		if trip.DepCode == "" || trip.ArrCode == "" || r.Direct(trip.DepCode, trip.ArrCode) {
+			connected = append(connected, trip)
+			continue
+		}
+		hub, ok := r.Connection(trip.DepCode, trip.ArrCode)
+		if !ok {
+			connected = append(connected, trip)
+			continue
+		}
+		first, second := trip, trip
+		first.ArrCode, first.Via = hub, hub
+		second.DepCode, second.Via = hub, hub
+		second.Date = trip.Date.Add(HubConnection)
+		connected = append(connected, first, second)
+	}
+	return connected
+}
@@ -0,0 +1,82 @@
+package flight
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeRoutes(t *testing.T, rows ...string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "routes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fName := filepath.Join(dir, "routes.dat")
+	if err := ioutil.WriteFile(fName, []byte(strings.Join(rows, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fName
+}
+
+func TestLoadRoutes(t *testing.T) {
+	fName := writeRoutes(t,
+		"LH,3320,TXL,345,FRA,340,,0,320",
+		"LH,3320,FRA,340,JFK,3797,,0,744",
+		"UA,5209,FRA,340,JFK,3797,Y,0,777",
+		"XX,1,TXL,345,JFK,3797,,1,320",
+	)
+	routes, err := LoadRoutes(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !routes.Direct("TXL", "FRA") || !routes.Direct("FRA", "JFK") {
+		t.Error("zero stop routes are direct")
+	}
+	if routes.Direct("TXL", "JFK") {
+		t.Error("a one stop route is not direct")
+	}
+	if hub, ok := routes.Connection("TXL", "JFK"); !ok || hub != "FRA" {
+		t.Errorf("Connection(TXL, JFK) = %q %v, want FRA", hub, ok)
+	}
+}
+
+func TestLoadRoutesShortRow(t *testing.T) {
+	fName := writeRoutes(t, "LH,3320,TXL,345,FRA,340,,0")
+	if _, err := LoadRoutes(fName); err == nil || !strings.Contains(err.Error(), "expected 9 fields, got 8") {
+		t.Errorf("got %v, want a field count error", err)
+	}
+}
+
+func TestConnectOvernight(t *testing.T) {
+	fName := writeRoutes(t,
+		"LH,3320,TXL,345,FRA,340,,0,320",
+		"LH,3320,FRA,340,JFK,3797,,0,744",
+	)
+	routes, err := LoadRoutes(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaves := time.Date(2019, 3, 19, 21, 0, 0, 0, time.UTC)
+	trips := routes.Connect(Trips{{DepCode: "TXL", ArrCode: "JFK", Date: leaves, Tour: "US tour"}})
+	if len(trips) != 2 {
+		t.Fatalf("got %d trips, want the two segments", len(trips))
+	}
+	first, second := trips[0], trips[1]
+	if first.DepCode != "TXL" || first.ArrCode != "FRA" || second.DepCode != "FRA" || second.ArrCode != "JFK" {
+		t.Errorf("segments %s-%s %s-%s, want TXL-FRA FRA-JFK", first.DepCode, first.ArrCode, second.DepCode, second.ArrCode)
+	}
+	if first.Via != "FRA" || second.Via != "FRA" || second.Tour != "US tour" {
+		t.Errorf("both segments carry the hub and the trip's tour: %+v", trips)
+	}
+	if !first.Date.Equal(leaves) {
+		t.Errorf("first segment leaves %s, want %s", first.Date, leaves)
+	}
+	if want := "2019-03-20"; second.Date.Format("2006-01-02") != want {
+		t.Errorf("second segment leaves %s, want the next day %s", second.Date, want)
+	}
+}
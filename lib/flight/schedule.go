@@ -0,0 +1,94 @@
+package flight
+
+import (
+	"time"
+
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/region"
+)
+
+type Haul int
+
+const (
+	ShortHaul  Haul = iota // within the same UN subregion
+	MediumHaul             // within the same continent
+	LongHaul               // between continents, or when a country is unknown
+)
+
+/*
+Schedule decides when an artist travels around a gig.
+
+Most sources only list the gig date, so set times default to DefaultStart
+after local midnight and last DefaultLength. The artist leaves so as to
+land ArrivalBuffer before the gig starts, longer flights leaving more room
+(a long-haul gig usually means arriving the day before), and flies on
+DepartureDelay after the gig ends.
+
+The remaining fields set how gigs are clustered into tours and residencies.
+*/
+type Schedule struct {
+	DefaultStart   time.Duration
+	DefaultLength  time.Duration
+	ArrivalBuffer  map[Haul]time.Duration
+	DepartureDelay time.Duration
+
+	TourGap          time.Duration
+	MinTourGigs      int
+	ResidencyGap     time.Duration
+	MinResidencyGigs int
+}
+
+func DefaultSchedule() Schedule {
+	return Schedule{
+		DefaultStart:  23 * time.Hour,
+		DefaultLength: 6 * time.Hour,
+		ArrivalBuffer: map[Haul]time.Duration{
+			ShortHaul:  8 * time.Hour,
+			MediumHaul: 12 * time.Hour,
+			LongHaul:   30 * time.Hour,
+		},
+		DepartureDelay: 8 * time.Hour,
+
+		TourGap:          14 * 24 * time.Hour,
+		MinTourGigs:      3,
+		ResidencyGap:     10 * 24 * time.Hour,
+		MinResidencyGigs: 4,
+	}
+}
+
+// Gig start and end in the venue's time zone.
+func (s Schedule) gigTimes(e event.Event, date time.Time) (time.Time, time.Time) {
+	loc := e.Zone()
+	start := e.Start
+	if start.IsZero() {
+		midnight := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+		start = midnight.Add(s.DefaultStart)
+	}
+	end := e.End
+	if end.IsZero() || !end.After(start) {
+		end = start.Add(s.DefaultLength)
+	}
+	return start.In(loc), end.In(loc)
+}
+
+func (s Schedule) departFor(start time.Time, h Haul) time.Time {
+	return start.Add(-s.ArrivalBuffer[h])
+}
+
+func (s Schedule) departAfter(end time.Time) time.Time {
+	return end.Add(s.DepartureDelay)
+}
+
+func haul(regions region.Regions, from, to string) Haul {
+	r1, ok1 := regions.Lookup(from)
+	r2, ok2 := regions.Lookup(to)
+	switch {
+	case !ok1 || !ok2:
+		return LongHaul
+	case r1.Subregion == r2.Subregion:
+		return ShortHaul
+	case r1.Continent == r2.Continent:
+		return MediumHaul
+	}
+	return LongHaul
+}
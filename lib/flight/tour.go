@@ -0,0 +1,175 @@
+package flight
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/region"
+)
+
+type TourKind string
+
+const (
+	TourRun   TourKind = "tour"
+	Residency TourKind = "residency"
+)
+
+// A Tour is a run of gigs the planner treats as one trip away from home.
+type Tour struct {
+	// Name and first day, e.g. "Asia tour 2019-03-10", unique among the
+	// artist's tours as emissions are totalled by it.
+	Label string
+	Kind  TourKind
+	Start time.Time
+	End   time.Time
+	Dates []time.Time
+	// Airport a residency is played at, the artist's temporary base.
+	Base string
+}
+
+func (t Tour) contains(d time.Time) bool {
+	return !d.Before(t.Start) && !d.After(t.End)
+}
+
+/*
+Tour clustering:
+
+1. A residency is at least MinResidencyGigs gigs at the same airport, away
+from home, with no more than ResidencyGap between them. Other gigs may fall
+in between, the artist flies to those from the residency and back.
+2. A tour is at least MinTourGigs of the remaining gigs in a row on the same
+foreign continent, with no more than TourGap between them.
+*/
+func cluster(a ra.Artist, gigs []datedGig, regions region.Regions, s Schedule) []Tour {
+	var (
+		tours   = make([]Tour, 0)
+		inTour  = make(map[int]bool)
+		byCode  = make(map[string][]int)
+		airport []string
+	)
+	for i, g := range gigs {
+		code := g.event.AirCode
+		if code == "" || code == a.AirCode {
+			continue
+		}
+		if _, ok := byCode[code]; !ok {
+			airport = append(airport, code)
+		}
+		byCode[code] = append(byCode[code], i)
+	}
+	for _, code := range airport {
+		for _, run := range splitByGap(gigs, byCode[code], s.ResidencyGap) {
+			if len(run) < s.MinResidencyGigs {
+				continue
+			}
+			name := gigs[run[0]].event.City
+			if name == "" {
+				name = code
+			}
+			tours = append(tours, newTour(fmt.Sprintf("%s residency", name), Residency, datesOf(gigs, run), code))
+			for _, i := range run {
+				inTour[i] = true
+			}
+		}
+	}
+
+	home, _ := regions.Lookup(a.Country)
+	var run []int
+	var runContinent region.Continent
+	closeRun := func() {
+		if len(run) >= s.MinTourGigs {
+			tours = append(tours, newTour(fmt.Sprintf("%s tour", runContinent), TourRun, datesOf(gigs, run), ""))
+		}
+		run = nil
+	}
+	for i, g := range gigs {
+		if inTour[i] {
+			continue
+		}
+		r, ok := regions.Lookup(countryOf(g.event))
+		if !ok || r.Continent == home.Continent {
+			closeRun()
+			continue
+		}
+		if len(run) > 0 && (r.Continent != runContinent || g.date.Sub(gigs[run[len(run)-1]].date) > s.TourGap) {
+			closeRun()
+		}
+		run = append(run, i)
+		runContinent = r.Continent
+	}
+	closeRun()
+
+	sort.SliceStable(tours, func(i, j int) bool {
+		return tours[i].Start.Before(tours[j].Start)
+	})
+	// Residencies in one city starting the same day, numbered from the second
+	seen := make(map[string]int)
+	for i := range tours {
+		label := tours[i].Label
+		if seen[label]++; seen[label] > 1 {
+			tours[i].Label = fmt.Sprintf("%s #%d", label, seen[label])
+		}
+	}
+	return tours
+}
+
+func datesOf(gigs []datedGig, run []int) []time.Time {
+	var dates []time.Time
+	for _, i := range run {
+		dates = append(dates, gigs[i].date)
+	}
+	return dates
+}
+
+func newTour(name string, kind TourKind, dates []time.Time, base string) Tour {
+	start, end := dates[0], dates[len(dates)-1]
+	return Tour{
+		Label: fmt.Sprintf("%s %s", name, start.Format("2006-01-02")),
+		Kind:  kind,
+		Start: start,
+		End:   end,
+		Dates: dates,
+		Base:  base,
+	}
+}
+
+// Splits the gigs picked out by index into runs with no more than gap
+// between them.
+func splitByGap(gigs []datedGig, picked []int, gap time.Duration) [][]int {
+	var runs [][]int
+	var run []int
+	for _, i := range picked {
+		if len(run) > 0 && gigs[i].date.Sub(gigs[run[len(run)-1]].date) > gap {
+			runs = append(runs, run)
+			run = nil
+		}
+		run = append(run, i)
+	}
+	if len(run) > 0 {
+		runs = append(runs, run)
+	}
+	return runs
+}
+
+func tourOf(tours []Tour, d time.Time) (Tour, bool) {
+	for _, t := range tours {
+		for _, td := range t.Dates {
+			if td.Equal(d) {
+				return t, true
+			}
+		}
+	}
+	return Tour{}, false
+}
+
+// The residency the artist is staying at between two gigs, if any.
+func residencyBetween(tours []Tour, d1, d2 time.Time) (Tour, bool) {
+	for _, t := range tours {
+		if t.Kind == Residency && t.contains(d1) && t.contains(d2) {
+			return t, true
+		}
+	}
+	return Tour{}, false
+}
@@ -0,0 +1,172 @@
+package flight
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/region"
+)
+
+func TestCluster(t *testing.T) {
+	in := func(code, country, city string) event.Event {
+		e := gig(code, country, "")
+		e.City = city
+		return e
+	}
+	type dated struct {
+		date string
+		e    event.Event
+	}
+	tests := []struct {
+		name string
+		gigs []dated
+		want []string
+	}{
+		{
+			name: "weekly residency",
+			gigs: []dated{
+				{"2019-07-01", in("IBZ", "ES", "Ibiza")}, {"2019-07-08", in("IBZ", "ES", "Ibiza")},
+				{"2019-07-15", in("IBZ", "ES", "Ibiza")}, {"2019-07-22", in("IBZ", "ES", "Ibiza")},
+			},
+			want: []string{"Ibiza residency 2019-07-01 (residency at IBZ, 4 gigs)"},
+		},
+		{
+			name: "too few for a residency",
+			gigs: []dated{
+				{"2019-07-01", in("IBZ", "ES", "Ibiza")}, {"2019-07-08", in("IBZ", "ES", "Ibiza")},
+				{"2019-07-15", in("IBZ", "ES", "Ibiza")},
+			},
+		},
+		{
+			name: "residency broken by a long gap",
+			gigs: []dated{
+				{"2019-07-01", in("IBZ", "ES", "")}, {"2019-07-08", in("IBZ", "ES", "")},
+				{"2019-07-15", in("IBZ", "ES", "")}, {"2019-08-15", in("IBZ", "ES", "")},
+			},
+		},
+		{
+			name: "gigs at home are no residency",
+			gigs: []dated{
+				{"2019-07-01", in("TXL", "DE", "")}, {"2019-07-08", in("TXL", "DE", "")},
+				{"2019-07-15", in("TXL", "DE", "")}, {"2019-07-22", in("TXL", "DE", "")},
+			},
+		},
+		{
+			name: "tour abroad",
+			gigs: []dated{
+				{"2019-03-01", in("JFK", "US", "")}, {"2019-03-06", in("ORD", "US", "")},
+				{"2019-03-15", in("YUL", "CA", "")},
+			},
+			want: []string{"North America tour 2019-03-01 (tour, 3 gigs)"},
+		},
+		{
+			name: "tour split by a gap",
+			gigs: []dated{
+				{"2019-03-01", in("JFK", "US", "")}, {"2019-03-06", in("ORD", "US", "")},
+				{"2019-03-25", in("YUL", "CA", "")},
+			},
+		},
+		{
+			name: "tour ends changing continent",
+			gigs: []dated{
+				{"2019-03-01", in("JFK", "US", "")}, {"2019-03-06", in("ORD", "US", "")},
+				{"2019-03-10", in("NRT", "JP", "")}, {"2019-03-12", in("KIX", "JP", "")},
+				{"2019-03-14", in("ICN", "KR", "")},
+			},
+			want: []string{"Asia tour 2019-03-10 (tour, 3 gigs)"},
+		},
+		{
+			name: "gig at home breaks the tour",
+			gigs: []dated{
+				{"2019-03-01", in("JFK", "US", "")}, {"2019-03-06", in("ORD", "US", "")},
+				{"2019-03-08", in("CDG", "FR", "")}, {"2019-03-10", in("LAX", "US", "")},
+			},
+		},
+		{
+			name: "residency gigs are not toured",
+			gigs: []dated{
+				{"2019-05-03", in("LAS", "US", "Las Vegas")}, {"2019-05-05", in("SFO", "US", "")},
+				{"2019-05-10", in("LAS", "US", "Las Vegas")}, {"2019-05-12", in("LAX", "US", "")},
+				{"2019-05-17", in("LAS", "US", "Las Vegas")}, {"2019-05-24", in("LAS", "US", "Las Vegas")},
+			},
+			want: []string{"Las Vegas residency 2019-05-03 (residency at LAS, 4 gigs)"},
+		},
+		{
+			name: "two tours in one month",
+			gigs: []dated{
+				{"2019-03-01", in("JFK", "US", "")}, {"2019-03-04", in("ORD", "US", "")},
+				{"2019-03-06", in("LAX", "US", "")}, {"2019-03-09", in("TXL", "DE", "")},
+				{"2019-03-20", in("YUL", "CA", "")}, {"2019-03-23", in("YYZ", "CA", "")},
+				{"2019-03-26", in("JFK", "US", "")},
+			},
+			want: []string{"North America tour 2019-03-01 (tour, 3 gigs)", "North America tour 2019-03-20 (tour, 3 gigs)"},
+		},
+		{
+			name: "two residencies in one city",
+			gigs: []dated{
+				{"2019-10-04", in("LHR", "GB", "London")}, {"2019-10-04", in("LCY", "GB", "London")},
+				{"2019-10-11", in("LHR", "GB", "London")}, {"2019-10-11", in("LCY", "GB", "London")},
+				{"2019-10-18", in("LHR", "GB", "London")}, {"2019-10-18", in("LCY", "GB", "London")},
+				{"2019-10-25", in("LHR", "GB", "London")}, {"2019-10-25", in("LCY", "GB", "London")},
+			},
+			want: []string{"London residency 2019-10-04 (residency at LHR, 4 gigs)", "London residency 2019-10-04 #2 (residency at LCY, 4 gigs)"},
+		},
+		{
+			name: "unknown country breaks the tour",
+			gigs: []dated{
+				{"2019-03-01", in("JFK", "US", "")}, {"2019-03-03", in("XXX", "", "")},
+				{"2019-03-06", in("ORD", "US", "")}, {"2019-03-08", in("LAX", "US", "")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := ra.Artist{Name: "A", AirCode: "TXL", Country: "Germany", Events: ra.Events{}}
+			for _, g := range tt.gigs {
+				a.Events.Add(day(g.date), g.e)
+			}
+			var got []string
+			for _, tour := range cluster(a, gigsInOrder(a.Events), region.New(), DefaultSchedule()) {
+				desc := fmt.Sprintf("%s (%s, %d gigs)", tour.Label, tour.Kind, len(tour.Dates))
+				if tour.Base != "" {
+					desc = fmt.Sprintf("%s (%s at %s, %d gigs)", tour.Label, tour.Kind, tour.Base, len(tour.Dates))
+				}
+				got = append(got, desc)
+				if !tour.Start.Equal(tour.Dates[0]) || !tour.End.Equal(tour.Dates[len(tour.Dates)-1]) {
+					t.Errorf("%s runs %s to %s, not its first and last gig", tour.Label, tour.Start, tour.End)
+				}
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Gigs played away from a residency are flown to from its base and back.
+func TestPlanFromResidency(t *testing.T) {
+	a := ra.Artist{Name: "A", AirCode: "TXL", Country: "Germany", Events: ra.Events{}}
+	for _, d := range []string{"2019-07-01", "2019-07-08", "2019-07-15", "2019-07-22"} {
+		a.Events.Add(day(d), gig("IBZ", "ES", ""))
+	}
+	a.Events.Add(day("2019-07-11"), gig("LIS", "PT", ""))
+	trips, err := NewPlanner(region.New(), DefaultSchedule()).Plan(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, trip := range trips {
+		got = append(got, trip.DepCode+"-"+trip.ArrCode)
+	}
+	want := []string{"TXL-IBZ", "IBZ-LIS", "LIS-IBZ", "IBZ-TXL"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	for _, trip := range trips {
+		if trip.Tour != "IBZ residency 2019-07-01" {
+			t.Errorf("%s-%s is labelled %q, want the residency", trip.DepCode, trip.ArrCode, trip.Tour)
+		}
+	}
+}
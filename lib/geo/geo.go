@@ -0,0 +1,88 @@
+package geo
+
+import "math"
+
+const earthRadiusKm = 6371.0
+
+// Distance is the great-circle distance in km between two points.
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	phi1, phi2 := radians(lat1), radians(lat2)
+	dPhi := radians(lat2 - lat1)
+	dLambda := radians(lng2 - lng1)
+	a := math.Sin(dPhi/2)*math.Sin(dPhi/2) + math.Cos(phi1)*math.Cos(phi2)*math.Sin(dLambda/2)*math.Sin(dLambda/2)
+	return 2 * earthRadiusKm * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+func radians(deg float64) float64 { return deg * math.Pi / 180 }
+
+// Point is a position in degrees.
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+/*
+Arc interpolates the great circle from a to b, the shortest way around the
+globe, returning n+1 points including both ends. Drawn point to point it
+bends as a flight path does on a flat map.
+*/
+func Arc(a, b Point, n int) []Point {
+	if n < 1 {
+		n = 1
+	}
+	x1, y1, z1 := cartesian(a)
+	x2, y2, z2 := cartesian(b)
+	d := math.Acos(math.Max(-1, math.Min(1, x1*x2+y1*y2+z1*z2)))
+	if d == 0 || math.Abs(d-math.Pi) < 1e-9 {
+		// The same point, or antipodes with no single shortest way
+		return []Point{a, b}
+	}
+	points := make([]Point, 0, n+1)
+	for i := 0; i <= n; i++ {
+		f := float64(i) / float64(n)
+		s, t := math.Sin((1-f)*d)/math.Sin(d), math.Sin(f*d)/math.Sin(d)
+		x, y, z := s*x1+t*x2, s*y1+t*y2, s*z1+t*z2
+		points = append(points, Point{
+			Lat: degrees(math.Atan2(z, math.Sqrt(x*x+y*y))),
+			Lng: degrees(math.Atan2(y, x)),
+		})
+	}
+	points[0], points[n] = a, b
+	return points
+}
+
+/*
+SplitAtAntimeridian breaks a line where it crosses 180° longitude, ending
+one part and starting the next on either side of it, so maps do not draw
+a Pacific crossing the long way round.
+*/
+func SplitAtAntimeridian(line []Point) [][]Point {
+	if len(line) == 0 {
+		return nil
+	}
+	parts := [][]Point{{line[0]}}
+	for i := 1; i < len(line); i++ {
+		p, q := line[i-1], line[i]
+		if math.Abs(q.Lng-p.Lng) > 180 {
+			edge, unwrapped := 180.0, q.Lng+360
+			if p.Lng < 0 {
+				edge, unwrapped = -180, q.Lng-360
+			}
+			t := (edge - p.Lng) / (unwrapped - p.Lng)
+			lat := p.Lat + t*(q.Lat-p.Lat)
+			last := len(parts) - 1
+			parts[last] = append(parts[last], Point{Lat: lat, Lng: edge})
+			parts = append(parts, []Point{{Lat: lat, Lng: -edge}})
+		}
+		last := len(parts) - 1
+		parts[last] = append(parts[last], q)
+	}
+	return parts
+}
+
+func cartesian(p Point) (float64, float64, float64) {
+	lat, lng := radians(p.Lat), radians(p.Lng)
+	return math.Cos(lat) * math.Cos(lng), math.Cos(lat) * math.Sin(lng), math.Sin(lat)
+}
+
+func degrees(rad float64) float64 { return rad * 180 / math.Pi }
@@ -0,0 +1,86 @@
+package geo
+
+import (
+	"math"
+	"testing"
+)
+
+var (
+	akl = Point{Lat: -37.008, Lng: 174.792}
+	hnl = Point{Lat: 21.319, Lng: -157.925}
+	txl = Point{Lat: 52.560, Lng: 13.288}
+	ams = Point{Lat: 52.309, Lng: 4.764}
+)
+
+func TestDistance(t *testing.T) {
+	if d := Distance(txl.Lat, txl.Lng, ams.Lat, ams.Lng); math.Abs(d-577) > 5 {
+		t.Errorf("TXL-AMS is %.0f km, want about 577", d)
+	}
+	if d := Distance(akl.Lat, akl.Lng, akl.Lat, akl.Lng); d != 0 {
+		t.Errorf("a point is %.0f km from itself", d)
+	}
+}
+
+func TestArcShort(t *testing.T) {
+	arc := Arc(txl, ams, 8)
+	if len(arc) != 9 {
+		t.Fatalf("got %d points, want 9", len(arc))
+	}
+	if arc[0] != txl || arc[8] != ams {
+		t.Errorf("arc runs %v to %v, want %v to %v", arc[0], arc[8], txl, ams)
+	}
+	// Evenly spaced along the great circle
+	total := Distance(txl.Lat, txl.Lng, ams.Lat, ams.Lng)
+	for i := 1; i < len(arc); i++ {
+		step := Distance(arc[i-1].Lat, arc[i-1].Lng, arc[i].Lat, arc[i].Lng)
+		if math.Abs(step-total/8) > 0.1 {
+			t.Errorf("step %d is %.1f km, want %.1f", i, step, total/8)
+		}
+	}
+	if got := Arc(txl, txl, 8); len(got) != 2 || got[0] != txl || got[1] != txl {
+		t.Errorf("arc of a point = %v", got)
+	}
+}
+
+func TestSplitAtAntimeridian(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to Point
+		edge     float64
+	}{
+		{"eastbound", akl, hnl, 180},
+		{"westbound", hnl, akl, -180},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := SplitAtAntimeridian(Arc(tt.from, tt.to, 64))
+			if len(parts) != 2 {
+				t.Fatalf("got %d parts, want 2", len(parts))
+			}
+			first, second := parts[0], parts[1]
+			if first[0] != tt.from || second[len(second)-1] != tt.to {
+				t.Errorf("parts run %v to %v, want %v to %v", first[0], second[len(second)-1], tt.from, tt.to)
+			}
+			end, start := first[len(first)-1], second[0]
+			if end.Lng != tt.edge || start.Lng != -tt.edge {
+				t.Errorf("cut at %.1f and %.1f, want %.0f and %.0f", end.Lng, start.Lng, tt.edge, -tt.edge)
+			}
+			if end.Lat != start.Lat {
+				t.Errorf("cut at latitudes %f and %f, want them the same", end.Lat, start.Lat)
+			}
+			for _, part := range parts {
+				for i := 1; i < len(part); i++ {
+					if math.Abs(part[i].Lng-part[i-1].Lng) > 180 {
+						t.Errorf("part still jumps from %.1f to %.1f", part[i-1].Lng, part[i].Lng)
+					}
+				}
+			}
+		})
+	}
+	if parts := SplitAtAntimeridian(Arc(txl, ams, 8)); len(parts) != 1 || len(parts[0]) != 9 {
+		t.Errorf("a line not crossing is split into %d parts", len(parts))
+	}
+	if parts := SplitAtAntimeridian(nil); parts != nil {
+		t.Errorf("got %v for no line", parts)
+	}
+}
@@ -0,0 +1,165 @@
+package gigfile
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/event"
+)
+
+/*
+A Gig is one line of an artist's own gig list. CSV files need a header,
+
+	date,venue,city,country
+	2019-03-01,Berghain,Berlin,Germany
+
+JSON files hold an array of the same fields. Start and end are optional
+RFC 3339 set times, e.g. "2019-03-01T23:00:00+01:00".
+*/
+type Gig struct {
+	Date    string `json:"date"`
+	Venue   string `json:"venue"`
+	City    string `json:"city"`
+	Country string `json:"country"`
+	Start   string `json:"start,omitempty"`
+	End     string `json:"end,omitempty"`
+}
+
+// New returns a crawler reading events from a gig list file rather than RA,
+// so self-reported tours go through the same airports and planner.
+func New(fname string) crawler.Crawler {
+	return gigFile{fname: fname}
+}
+
+type gigFile struct {
+	fname string
+}
+
+// Every artist's events come from the one file.
+func (g gigFile) GetArtistUrl(string) (string, error) { return g.fname, nil }
+
+func (g gigFile) ArtistUrlFromSlug(string) string { return g.fname }
+
+/*
+Loads the gigs in fname played in tourYear, or all of them if it is empty.
+Several gigs on one day are all kept, the same place and set time listed
+twice is taken for a mistake in the file.
+*/
+func (g gigFile) GetArtistEvents(_ context.Context, fname, tourYear string) (crawler.Events, error) {
+	events := make(crawler.Events)
+	gigs, err := Load(fname)
+	if err != nil {
+		return events, err
+	}
+	seen := make(map[string]int)
+	for i, gig := range gigs {
+		date, e, err := gig.event()
+		if err != nil {
+			return events, fmt.Errorf("%s: gig %d: %v", fname, i+1, err)
+		}
+		if tourYear != "" && fmt.Sprintf("%d", date.Year()) != tourYear {
+			continue
+		}
+		key := date.Format("2006-01-02") + "|" + strings.ToLower(e.Location) + "|" + e.Start.String()
+		if first, ok := seen[key]; ok {
+			return events, fmt.Errorf("%s: gigs %d and %d are both at %s on %s", fname, first, i+1, e.Location, gig.Date)
+		}
+		seen[key] = i + 1
+		events.Add(date, e)
+	}
+	return events, nil
+}
+
+func Load(fname string) ([]Gig, error) {
+	var gigs []Gig
+	file, err := os.Open(fname)
+	if err != nil {
+		return gigs, err
+	}
+	defer file.Close()
+	if strings.EqualFold(filepath.Ext(fname), ".json") {
+		err = json.NewDecoder(file).Decode(&gigs)
+		return gigs, err
+	}
+	return readCSV(file)
+}
+
+func readCSV(r io.Reader) ([]Gig, error) {
+	var gigs []Gig
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return gigs, err
+	}
+	if len(records) == 0 {
+		return gigs, nil
+	}
+	columns := records[0]
+	for _, record := range records[1:] {
+		var gig Gig
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+			switch strings.ToLower(strings.TrimSpace(columns[i])) {
+			case "date":
+				gig.Date = value
+			case "venue":
+				gig.Venue = value
+			case "city":
+				gig.City = value
+			case "country":
+				gig.Country = value
+			case "start":
+				gig.Start = value
+			case "end":
+				gig.End = value
+			}
+		}
+		gigs = append(gigs, gig)
+	}
+	return gigs, nil
+}
+
+func (g Gig) event() (time.Time, event.Event, error) {
+	var e event.Event
+	date, err := time.Parse("2006-01-02", strings.TrimSpace(g.Date))
+	if err != nil {
+		return date, e, err
+	}
+	var place []string
+	for _, p := range []string{g.Venue, g.City, g.Country} {
+		if p = strings.TrimSpace(p); p != "" {
+			place = append(place, p)
+		}
+	}
+	if len(place) == 0 {
+		return date, e, fmt.Errorf("no venue or city for %s", g.Date)
+	}
+	e = event.Event{
+		Title:    g.Venue,
+		Venue:    g.Venue,
+		Location: strings.Join(place, ", "),
+	}
+	if g.Start != "" {
+		if e.Start, err = time.Parse(time.RFC3339, g.Start); err != nil {
+			return date, e, err
+		}
+	}
+	if g.End != "" {
+		if e.End, err = time.Parse(time.RFC3339, g.End); err != nil {
+			return date, e, err
+		}
+	}
+	return date, e, nil
+}
@@ -0,0 +1,57 @@
+package gigfile
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "gigfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fName := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(fName, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fName
+}
+
+func TestGetArtistEvents(t *testing.T) {
+	fName := writeFile(t, "gigs.csv", `date,venue,city,country,start
+2019-03-01,Berghain,Berlin,Germany,
+2019-07-06,Melt,Gräfenhainichen,Germany,2019-07-06T16:00:00+02:00
+2019-07-06,Tresor,Berlin,Germany,2019-07-06T23:30:00+02:00
+2020-01-01,Fabric,London,UK,
+`)
+	events, err := New(fName).GetArtistEvents(context.Background(), fName, "2019")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events.Count() != 3 {
+		t.Fatalf("got %d gigs, want the three in 2019", events.Count())
+	}
+	day := events[time.Date(2019, 7, 6, 0, 0, 0, 0, time.UTC)]
+	if len(day) != 2 || day[0].Venue != "Melt" || day[1].Venue != "Tresor" {
+		t.Errorf("want both gigs on 2019-07-06 in file order, got %+v", day)
+	}
+}
+
+func TestGetArtistEventsRepeated(t *testing.T) {
+	fName := writeFile(t, "gigs.json", `[
+	{"date": "2019-03-01", "venue": "Berghain", "city": "Berlin"},
+	{"date": "2019-03-02", "venue": "Tresor", "city": "Berlin"},
+	{"date": "2019-03-01", "venue": "berghain", "city": "Berlin"}
+]`)
+	_, err := New(fName).GetArtistEvents(context.Background(), fName, "")
+	if err == nil || !strings.Contains(err.Error(), "gigs 1 and 3") {
+		t.Errorf("want an error naming gigs 1 and 3, got %v", err)
+	}
+}
@@ -1,27 +1,32 @@
 package google
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
 	"net/url"
 	"strings"
+
+	"github.com/cleanscene.flights/lib/throttle"
 )
 
 type Places interface {
-	QueryCoordinates(string, QueryType) (float64, float64, error)
+	QueryCoordinates(context.Context, string, QueryType) (float64, float64, error)
 }
 
 type Api struct {
 	key           string
 	hostCoordUrl  string
 	hostCoordText string
+	client        throttle.Client
 }
 
-func NewApi(key string) Places {
+// NewApi queries google places through client, nil for the default client.
+func NewApi(key string, client throttle.Client) Places {
 	return Api{
 		key:           key,
+		client:        client,
 		hostCoordUrl:  "https://maps.googleapis.com/maps/api/place/textsearch/json?query=",
 		hostCoordText: "https://maps.googleapis.com/maps/api/place/findplacefromtext/json?input=",
 	}
@@ -57,7 +62,7 @@ var (
 	textParams  = "inputtype=textquery&fields=geometry&key="
 )
 
-func (api Api) QueryCoordinates(place string, queryType QueryType) (float64, float64, error) {
+func (api Api) QueryCoordinates(ctx context.Context, place string, queryType QueryType) (float64, float64, error) {
 	var (
 		rr    = PlaceResp{}
 		query string
@@ -69,7 +74,7 @@ func (api Api) QueryCoordinates(place string, queryType QueryType) (float64, flo
 		place = strings.ReplaceAll(place, ",", "")
 		query = fmt.Sprintf("%s%s&%s%s", api.hostCoordText, textParams, url.PathEscape(place), api.key)
 	}
-	resp, err := http.Get(query)
+	resp, err := throttle.Get(ctx, api.client, query)
 	if err != nil {
 		return 0, 0, err
 	}
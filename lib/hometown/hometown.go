@@ -0,0 +1,242 @@
+package hometown
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/cleanscene.flights/lib/region"
+	"github.com/cleanscene.flights/lib/registry"
+	"github.com/cleanscene.flights/lib/throttle"
+)
+
+// Location is where an artist says they are based.
+type Location struct {
+	City    string
+	Country string
+}
+
+// Known is true when both the city and country were found.
+func (l Location) Known() bool {
+	return l.City != "" && l.Country != ""
+}
+
+var ErrNotFound = errors.New("hometown not visible on soundcloud or ra")
+
+type Finder interface {
+	// Find looks up an artist's hometown on their SoundCloud and RA profiles.
+	Find(context.Context, registry.Entry) (Location, registry.Source, error)
+}
+
+// New creates a finder reading RA profiles from raUrl, e.g.
+// https://www.residentadvisor.net, through client, nil for the default.
+func New(raUrl string, client throttle.Client) Finder {
+	return finder{raUrl: raUrl, regions: region.New(), client: client}
+}
+
+type finder struct {
+	raUrl   string
+	regions region.Regions
+	client  throttle.Client
+}
+
+func (f finder) Find(ctx context.Context, entry registry.Entry) (Location, registry.Source, error) {
+	var sc, ra Location
+	if entry.SoundCloudUrl != "" {
+		page, err := f.fetch(ctx, entry.SoundCloudUrl)
+		if err == nil {
+			sc, err = ParseSoundCloud(page, f.regions)
+			page.Close()
+		}
+		if err != nil {
+			fmt.Printf("soundcloud profile of %s: %v\n", entry.Name, err)
+		}
+	}
+	if entry.RASlug != "" {
+		page, err := f.fetch(ctx, fmt.Sprintf("%s/dj/%s", f.raUrl, entry.RASlug))
+		if err == nil {
+			ra, err = ParseRA(page, f.regions)
+			page.Close()
+		}
+		if err != nil {
+			fmt.Printf("ra profile of %s: %v\n", entry.Name, err)
+		}
+	}
+	loc, src, ok := Resolve(sc, ra)
+	if !ok {
+		return loc, src, ErrNotFound
+	}
+	return loc, src, nil
+}
+
+/*
+Resolve picks the hometown the way the study did, first from SoundCloud, then
+from RA. A source naming only the country can still fill in the country of
+the other's city.
+*/
+func Resolve(soundCloud, ra Location) (Location, registry.Source, bool) {
+	if soundCloud.City != "" {
+		if soundCloud.Country == "" {
+			soundCloud.Country = ra.Country
+		}
+		if soundCloud.Known() {
+			return soundCloud, registry.SoundCloud, true
+		}
+	}
+	if ra.City != "" {
+		if ra.Country == "" {
+			ra.Country = soundCloud.Country
+		}
+		if ra.Known() {
+			return ra, registry.RA, true
+		}
+	}
+	return Location{}, "", false
+}
+
+// SoundCloud embeds the profile as json for its web app to hydrate.
+var hydration = regexp.MustCompile(`(?s)window\.__sc_hydration\s*=\s*(\[.*?\]);\s*</script>`)
+
+// ParseSoundCloud reads the city and country code off a saved profile page.
+func ParseSoundCloud(r io.Reader, regions region.Regions) (Location, error) {
+	var loc Location
+	page, err := ioutil.ReadAll(r)
+	if err != nil {
+		return loc, err
+	}
+	match := hydration.FindSubmatch(page)
+	if match == nil {
+		return loc, errors.New("no profile data on soundcloud page")
+	}
+	var hydratables []struct {
+		Hydratable string          `json:"hydratable"`
+		Data       json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(match[1], &hydratables); err != nil {
+		return loc, fmt.Errorf("soundcloud profile data: %v", err)
+	}
+	for _, h := range hydratables {
+		if h.Hydratable != "user" {
+			continue
+		}
+		var user struct {
+			City        string `json:"city"`
+			CountryCode string `json:"country_code"`
+		}
+		if err := json.Unmarshal(h.Data, &user); err != nil {
+			return loc, fmt.Errorf("soundcloud user: %v", err)
+		}
+		loc.City = strings.TrimSpace(user.City)
+		loc.Country = countryName(user.CountryCode, regions)
+		return loc, nil
+	}
+	return loc, errors.New("no user on soundcloud page")
+}
+
+// Labels RA puts in front of where an artist is based.
+var raLabels = []string{"location", "based in", "hometown", "country"}
+
+/*
+ParseRA reads the location off a saved RA artist profile, listed under the
+artist's details as e.g.
+
+	<li><div>Country /</div><a href="/dj.aspx?country=de">Germany</a></li>
+	<li><div>Location /</div>Berlin, Germany</li>
+*/
+func ParseRA(r io.Reader, regions region.Regions) (Location, error) {
+	var loc Location
+	document, err := goquery.NewDocumentFromReader(r)
+	if err != nil {
+		return loc, err
+	}
+	document.Find("li, dt, div, span").Each(func(i int, s *goquery.Selection) {
+		if s.Children().Length() > 0 {
+			return
+		}
+		label := strings.ToLower(strings.Trim(strings.TrimSpace(s.Text()), " /:"))
+		for _, l := range raLabels {
+			if label != l {
+				continue
+			}
+			value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(labelled(s)), strings.TrimSpace(s.Text())))
+			found := parseLocation(value, l == "country", regions)
+			if loc.City == "" {
+				loc.City = found.City
+			}
+			if loc.Country == "" {
+				loc.Country = found.Country
+			}
+		}
+	})
+	if loc.City == "" && loc.Country == "" {
+		return loc, errors.New("no location on ra page")
+	}
+	return loc, nil
+}
+
+// The value is in the label's parent, or in the next element for dt/dd lists.
+func labelled(label *goquery.Selection) string {
+	if goquery.NodeName(label) == "dt" {
+		return label.Next().Text()
+	}
+	return label.Parent().Text()
+}
+
+/*
+Splits "Berlin, Germany" or a lone city or country. Nothing is taken from a
+value naming a country that cannot be looked up, rather than writing an
+unknown country, or a country under a country label, into the registry as
+the artist's city.
+*/
+func parseLocation(value string, onlyCountry bool, regions region.Regions) Location {
+	value = strings.Join(strings.Fields(value), " ")
+	if value == "" {
+		return Location{}
+	}
+	i := strings.LastIndex(value, ",")
+	if i < 0 {
+		if country := countryName(value, regions); country != "" {
+			return Location{Country: country}
+		}
+		if onlyCountry {
+			return Location{}
+		}
+		return Location{City: value}
+	}
+	country := countryName(value[i+1:], regions)
+	if country == "" {
+		return Location{}
+	}
+	city := strings.TrimSpace(value[:i])
+	if onlyCountry {
+		city = ""
+	}
+	return Location{City: city, Country: country}
+}
+
+func countryName(country string, regions region.Regions) string {
+	r, ok := regions.Lookup(country)
+	if !ok {
+		return ""
+	}
+	return r.Name
+}
+
+func (f finder) fetch(ctx context.Context, url string) (io.ReadCloser, error) {
+	resp, err := throttle.Get(ctx, f.client, url)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,189 @@
+package hometown
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cleanscene.flights/lib/airports"
+	"github.com/cleanscene.flights/lib/region"
+	"github.com/cleanscene.flights/lib/registry"
+)
+
+// An airport list in the layout of the one fly loads, code - name.
+const airportList = `TXL - BERLIN TEGEL GERMANY
+FRA - FRANKFURT GERMANY
+LHR - LONDON HEATHROW UNITED KINGDOM
+`
+
+func airportService(t *testing.T) airports.Airports {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "hometown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fName := filepath.Join(dir, "airports.txt")
+	if err := ioutil.WriteFile(fName, []byte(airportList), 0644); err != nil {
+		t.Fatal(err)
+	}
+	airSvc, err := airports.New(fName, "", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return airSvc
+}
+
+// Every saved profile page, read by the parser for its site and resolved on
+// its own, gives the hometown and the airport the artist flies from.
+func TestFixtures(t *testing.T) {
+	want := map[string]struct {
+		city, country, airport string
+		source                 registry.Source
+	}{
+		"ra-profile.html":         {"Berlin", "Germany", "TXL", registry.RA},
+		"soundcloud-profile.html": {"Berlin", "Germany", "TXL", registry.SoundCloud},
+	}
+	files, err := filepath.Glob(filepath.Join("..", "..", "fixtures", "hometown", "*.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no fixtures")
+	}
+	regions, airSvc := region.New(), airportService(t)
+	for _, fName := range files {
+		name := filepath.Base(fName)
+		t.Run(name, func(t *testing.T) {
+			w, ok := want[name]
+			if !ok {
+				t.Fatalf("no expected hometown for %s", name)
+			}
+			page, err := os.Open(fName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer page.Close()
+
+			var sc, ra Location
+			switch {
+			case strings.HasPrefix(name, "ra-"):
+				ra, err = ParseRA(page, regions)
+			case strings.HasPrefix(name, "soundcloud-"):
+				sc, err = ParseSoundCloud(page, regions)
+			default:
+				t.Fatalf("no parser for %s", name)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			loc, src, ok := Resolve(sc, ra)
+			if !ok {
+				t.Fatal("not resolved")
+			}
+			if loc.City != w.city || loc.Country != w.country || src != w.source {
+				t.Errorf("got %s, %s from %s, want %s, %s from %s", loc.City, loc.Country, src, w.city, w.country, w.source)
+			}
+			airport, err := airSvc.AirCodeByCity(loc.City, loc.Country)
+			if err != nil || airport != w.airport {
+				t.Errorf("airport %s %v, want %s", airport, err, w.airport)
+			}
+		})
+	}
+}
+
+func TestParseLocation(t *testing.T) {
+	regions := region.New()
+	tests := []struct {
+		value       string
+		onlyCountry bool
+		want        Location
+	}{
+		{"Berlin, Germany", false, Location{"Berlin", "Germany"}},
+		{"  London,  UK ", false, Location{"London", "United Kingdom"}},
+		{"Rotterdam, Holland", false, Location{"Rotterdam", "Netherlands"}},
+		{"Germany", false, Location{Country: "Germany"}},
+		{"Berlin", false, Location{City: "Berlin"}},
+		{"Holland", true, Location{Country: "Netherlands"}},
+		{"Atlantis", true, Location{}},
+		{"Berlin, Germany", true, Location{Country: "Germany"}},
+		{"Poseidonia, Atlantis", false, Location{}},
+		{"", false, Location{}},
+	}
+	for _, tt := range tests {
+		if got := parseLocation(tt.value, tt.onlyCountry, regions); got != tt.want {
+			t.Errorf("parseLocation(%q, %v) = %+v, want %+v", tt.value, tt.onlyCountry, got, tt.want)
+		}
+	}
+}
+
+// Profiles are fetched through the client given, and not at all once the
+// context is cancelled.
+func TestFind(t *testing.T) {
+	page, err := ioutil.ReadFile(filepath.Join("..", "..", "fixtures", "hometown", "ra-profile.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/dj/someone" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(page)
+	}))
+	defer srv.Close()
+	finder := New(srv.URL, srv.Client())
+
+	loc, src, err := finder.Find(context.Background(), registry.Entry{Name: "Someone", RASlug: "someone"})
+	if err != nil || loc.City != "Berlin" || src != registry.RA {
+		t.Errorf("got %+v from %s, %v, want Berlin from ra", loc, src, err)
+	}
+	if _, _, err := finder.Find(context.Background(), registry.Entry{Name: "Nobody", RASlug: "nobody"}); err != ErrNotFound {
+		t.Errorf("got %v for a missing profile, want ErrNotFound", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	requests = 0
+	if _, _, err := finder.Find(ctx, registry.Entry{Name: "Someone", RASlug: "someone"}); err != ErrNotFound || requests != 0 {
+		t.Errorf("got %v after %d requests, want nothing fetched once cancelled", err, requests)
+	}
+}
+
+func TestParseRAUnknownCountry(t *testing.T) {
+	page := `<ul>
+<li><div>Country /</div>Atlantis</li>
+<li><div>Location /</div>Poseidonia, Atlantis</li>
+</ul>`
+	if loc, err := ParseRA(strings.NewReader(page), region.New()); err == nil {
+		t.Errorf("got %+v, want no location", loc)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		sc, ra  Location
+		want    Location
+		source  registry.Source
+		resolve bool
+	}{
+		{"soundcloud first", Location{"Berlin", "Germany"}, Location{"London", "United Kingdom"}, Location{"Berlin", "Germany"}, registry.SoundCloud, true},
+		{"country from ra", Location{City: "Berlin"}, Location{Country: "Germany"}, Location{"Berlin", "Germany"}, registry.SoundCloud, true},
+		{"ra city", Location{Country: "Germany"}, Location{City: "Berlin"}, Location{"Berlin", "Germany"}, registry.RA, true},
+		{"countries only", Location{Country: "Germany"}, Location{Country: "Germany"}, Location{}, "", false},
+	}
+	for _, tt := range tests {
+		got, src, ok := Resolve(tt.sc, tt.ra)
+		if got != tt.want || src != tt.source || ok != tt.resolve {
+			t.Errorf("%s: got %+v from %q %v, want %+v from %q %v", tt.name, got, src, ok, tt.want, tt.source, tt.resolve)
+		}
+	}
+}
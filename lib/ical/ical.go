@@ -0,0 +1,238 @@
+package ical
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/throttle"
+)
+
+// The parts of a VEVENT needed to place a gig.
+type VEvent struct {
+	UID      string
+	Summary  string
+	Location string
+	Start    time.Time
+	End      time.Time
+	// Floating times carry no time zone, only the date is reliable.
+	Floating bool
+	AllDay   bool
+	Lat      float64
+	Lng      float64
+	HasGeo   bool
+}
+
+// Parse reads the VEVENTs of an iCalendar (RFC 5545) stream.
+func Parse(r io.Reader) ([]VEvent, error) {
+	var (
+		events  []VEvent
+		current *VEvent
+	)
+	lines, err := unfold(r)
+	if err != nil {
+		return events, err
+	}
+	for _, l := range lines {
+		lineNum := l.num
+		name, params, value, ok := split(l.text)
+		if !ok {
+			continue
+		}
+		switch {
+		case name == "BEGIN" && strings.EqualFold(value, "VEVENT"):
+			current = &VEvent{}
+		case name == "END" && strings.EqualFold(value, "VEVENT"):
+			if current != nil {
+				events = append(events, *current)
+			}
+			current = nil
+		case current == nil:
+			continue
+		case name == "UID":
+			current.UID = value
+		case name == "SUMMARY":
+			current.Summary = unescape(value)
+		case name == "LOCATION":
+			current.Location = unescape(value)
+		case name == "GEO":
+			coords := strings.Split(value, ";")
+			if len(coords) != 2 {
+				return events, fmt.Errorf("line %d: bad GEO %q", lineNum, value)
+			}
+			current.Lat, err = strconv.ParseFloat(coords[0], 64)
+			if err == nil {
+				current.Lng, err = strconv.ParseFloat(coords[1], 64)
+			}
+			if err != nil {
+				return events, fmt.Errorf("line %d: bad GEO %q: %v", lineNum, value, err)
+			}
+			current.HasGeo = true
+		case name == "DTSTART":
+			current.Start, current.Floating, current.AllDay, err = parseTime(params, value)
+			if err != nil {
+				return events, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+		case name == "DTEND":
+			current.End, _, _, err = parseTime(params, value)
+			if err != nil {
+				return events, fmt.Errorf("line %d: %v", lineNum, err)
+			}
+		}
+	}
+	return events, nil
+}
+
+// A content line unfolded, with the line of the file it starts on.
+type contentLine struct {
+	text string
+	num  int
+}
+
+// Long lines are folded onto continuation lines starting with a space or tab.
+func unfold(r io.Reader) ([]contentLine, error) {
+	var (
+		lines []contentLine
+		num   int
+	)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		num++
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) > 0 && (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) {
+			lines[len(lines)-1].text += line[1:]
+			continue
+		}
+		lines = append(lines, contentLine{text: line, num: num})
+	}
+	return lines, scanner.Err()
+}
+
+// Splits NAME;PARAM=x;PARAM=y:VALUE
+func split(line string) (string, map[string]string, string, bool) {
+	params := make(map[string]string)
+	colon := strings.Index(line, ":")
+	if colon < 0 {
+		return "", params, "", false
+	}
+	parts := strings.Split(line[:colon], ";")
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], true
+}
+
+func parseTime(params map[string]string, value string) (time.Time, bool, bool, error) {
+	if params["VALUE"] == "DATE" || len(value) == 8 {
+		t, err := time.Parse("20060102", value)
+		return t, true, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, false, err
+	}
+	if tzid, ok := params["TZID"]; ok {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			t, err := time.ParseInLocation("20060102T150405", value, loc)
+			return t, false, false, err
+		}
+	}
+	t, err := time.Parse("20060102T150405", value)
+	return t, true, false, err
+}
+
+var unescaper = strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`)
+
+func unescape(value string) string {
+	return unescaper.Replace(value)
+}
+
+// New returns a crawler reading events from an iCalendar feed, a file path
+// or http(s)/webcal url, in place of the RA crawler.
+func New(feed string) crawler.Crawler {
+	return feedCrawler{feed: feed}
+}
+
+type feedCrawler struct {
+	feed string
+}
+
+func (c feedCrawler) GetArtistUrl(string) (string, error) { return c.feed, nil }
+
+func (c feedCrawler) ArtistUrlFromSlug(string) string { return c.feed }
+
+func (c feedCrawler) GetArtistEvents(ctx context.Context, feed, tourYear string) (crawler.Events, error) {
+	events := make(crawler.Events)
+	r, err := open(ctx, feed)
+	if err != nil {
+		return events, err
+	}
+	defer r.Close()
+	vevents, err := Parse(r)
+	if err != nil {
+		return events, fmt.Errorf("%s: %v", feed, err)
+	}
+	for _, v := range vevents {
+		if v.Start.IsZero() {
+			continue
+		}
+		date, e := v.Event()
+		if tourYear != "" && strconv.Itoa(date.Year()) != tourYear {
+			continue
+		}
+		events.Add(date, e)
+	}
+	return events, nil
+}
+
+// Event maps a VEVENT onto the event model, keyed by its date like RA events.
+func (v VEvent) Event() (time.Time, event.Event) {
+	date := time.Date(v.Start.Year(), v.Start.Month(), v.Start.Day(), 0, 0, 0, 0, time.UTC)
+	e := event.Event{
+		Title:    v.Summary,
+		Location: v.Location,
+	}
+	// Feeds often only name the venue in the summary
+	if e.Location == "" {
+		e.Location = v.Summary
+	}
+	if v.HasGeo {
+		e.Lat, e.Lng = v.Lat, v.Lng
+	}
+	// Without a time zone the set times cannot be placed, the planner assumes them.
+	if !v.Floating {
+		e.Start, e.End = v.Start, v.End
+	}
+	return date, e
+}
+
+func open(ctx context.Context, feed string) (io.ReadCloser, error) {
+	if strings.HasPrefix(feed, "webcal://") {
+		feed = "https://" + strings.TrimPrefix(feed, "webcal://")
+	}
+	if !strings.HasPrefix(feed, "http://") && !strings.HasPrefix(feed, "https://") {
+		return os.Open(feed)
+	}
+	resp, err := throttle.Get(ctx, nil, feed)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, errors.New(resp.Status)
+	}
+	return resp.Body, nil
+}
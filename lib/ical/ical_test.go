@@ -0,0 +1,124 @@
+package ical
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func parse(t *testing.T, lines ...string) []VEvent {
+	t.Helper()
+	events, err := Parse(strings.NewReader(strings.Join(lines, "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestParseFolded(t *testing.T) {
+	events := parse(t,
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"SUMMARY:Klubnacht at Ber",
+		" ghain",
+		"LOCATION:Am Wriezener Bahnhof\\, ",
+		"\t10243 Berlin",
+		"DTSTART:20190301T230000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	)
+	if len(events) != 1 {
+		t.Fatalf("got %d events, want 1", len(events))
+	}
+	if got := events[0].Summary; got != "Klubnacht at Berghain" {
+		t.Errorf("summary %q", got)
+	}
+	if got := events[0].Location; got != "Am Wriezener Bahnhof, 10243 Berlin" {
+		t.Errorf("location %q", got)
+	}
+}
+
+func TestParseTimes(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	tests := []struct {
+		name     string
+		dtstart  string
+		want     time.Time
+		floating bool
+		allDay   bool
+	}{
+		{"utc", "DTSTART:20190301T230000Z", time.Date(2019, 3, 1, 23, 0, 0, 0, time.UTC), false, false},
+		{"tzid", "DTSTART;TZID=Europe/Berlin:20190301T230000", time.Date(2019, 3, 1, 23, 0, 0, 0, berlin), false, false},
+		{"quoted tzid", `DTSTART;TZID="Europe/Berlin":20190301T230000`, time.Date(2019, 3, 1, 23, 0, 0, 0, berlin), false, false},
+		{"unknown tzid", "DTSTART;TZID=Mars/Olympus:20190301T230000", time.Date(2019, 3, 1, 23, 0, 0, 0, time.UTC), true, false},
+		{"floating", "DTSTART:20190301T230000", time.Date(2019, 3, 1, 23, 0, 0, 0, time.UTC), true, false},
+		{"all day", "DTSTART;VALUE=DATE:20190301", time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC), true, true},
+		{"all day without value", "DTSTART:20190301", time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC), true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events := parse(t, "BEGIN:VEVENT", tt.dtstart, "END:VEVENT")
+			if len(events) != 1 {
+				t.Fatalf("got %d events, want 1", len(events))
+			}
+			v := events[0]
+			if !v.Start.Equal(tt.want) || v.Floating != tt.floating || v.AllDay != tt.allDay {
+				t.Errorf("got %s floating %v all day %v, want %s floating %v all day %v",
+					v.Start, v.Floating, v.AllDay, tt.want, tt.floating, tt.allDay)
+			}
+			date, e := v.Event()
+			if !date.Equal(time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("dated %s, want 2019-03-01", date)
+			}
+			if e.Start.IsZero() != tt.floating {
+				t.Errorf("set time %s kept for a floating time %v", e.Start, tt.floating)
+			}
+		})
+	}
+}
+
+func TestParseGeo(t *testing.T) {
+	events := parse(t, "BEGIN:VEVENT", "GEO:52.511;13.443", "END:VEVENT")
+	if len(events) != 1 || !events[0].HasGeo || events[0].Lat != 52.511 || events[0].Lng != 13.443 {
+		t.Errorf("got %+v", events)
+	}
+}
+
+// Errors name the line of the file the property starts on, counting the
+// continuation lines folded into those before it.
+func TestParseBadGeo(t *testing.T) {
+	tests := []struct {
+		name string
+		geo  string
+		want string
+	}{
+		{"one coordinate", "GEO:52.511", "line 6: bad GEO"},
+		{"not a number", "GEO:north;13.443", "line 6: bad GEO"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(strings.Join([]string{
+				"BEGIN:VCALENDAR",
+				"BEGIN:VEVENT",
+				"DESCRIPTION:A long",
+				"  description",
+				"  folded twice",
+				tt.geo,
+				"END:VEVENT",
+			}, "\r\n")))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("got %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBadTimeLine(t *testing.T) {
+	_, err := Parse(strings.NewReader("BEGIN:VEVENT\nSUMMARY:A\n  B\nDTSTART;TZID=Europe/Berlin:\n 2019030\nEND:VEVENT\n"))
+	if err == nil || !strings.HasPrefix(err.Error(), "line 4:") {
+		t.Errorf("got %v, want an error on line 4", err)
+	}
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/flight"
+)
+
+// Tour files follow the flight schema, see SchemaVersion.
+var TourHeaders = []string{"schema_version", "tour", "kind", "start", "end", "gigs", "flights", "offset_eur", "carbon_kg", "carbon_per_person_kg", "fuel_l", "distance_km", "methodology"}
+
+// Write emissions per tour and residency, flights outside of any tour are totalled last.
+func WriteTours(outputs []atmos.Output, tours []flight.Tour, fName string) error {
+	csvfile, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer csvfile.Close()
+	csvwriter := csv.NewWriter(csvfile)
+	csvwriter.Write(TourHeaders)
+
+	totals := make(map[string]atmos.Output)
+	flights := make(map[string]int)
+	perPerson := make(map[string]float64)
+	for _, output := range outputs {
+		total := totals[output.Tour]
+		total.OffsetEuros += output.OffsetEuros
+		total.CarbonOutput += output.CarbonOutput
+		total.FuelInLiter += output.FuelInLiter
+		total.Distance += output.Distance
+		totals[output.Tour] = total
+		flights[output.Tour]++
+		perPerson[output.Tour] += output.CarbonPerPerson()
+	}
+	row := func(label, kind, start, end string, gigs int) []string {
+		total := totals[label]
+		return []string{
+			strconv.Itoa(SchemaVersion),
+			label,
+			kind,
+			start,
+			end,
+			fmt.Sprintf("%d", gigs),
+			fmt.Sprintf("%d", flights[label]),
+			fmt.Sprintf("%f", total.OffsetEuros),
+			fmt.Sprintf("%f", total.CarbonOutput),
+			fmt.Sprintf("%f", perPerson[label]),
+			fmt.Sprintf("%f", total.FuelInLiter),
+			fmt.Sprintf("%d", total.Distance),
+			atmos.Methodology,
+		}
+	}
+	for _, tour := range tours {
+		csvwriter.Write(row(tour.Label, string(tour.Kind), tour.Start.Format("2006-01-02"), tour.End.Format("2006-01-02"), len(tour.Dates)))
+	}
+	if flights[""] > 0 {
+		csvwriter.Write(row("", "none", "", "", 0))
+	}
+
+	csvwriter.Flush()
+	return csvwriter.Error()
+}
@@ -0,0 +1,405 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/flight"
+)
+
+/*
+SchemaVersion of the per-artist flight files, written on every row. Version 1
+is the original csv with units in the values ("€1.2", "300 kg"), it has no
+version column and is still read, see ReadFlights.
+*/
+const SchemaVersion = 2
+
+// Which way a flight goes between the artist's home and their gigs.
+type Leg string
+
+const (
+	LegOutbound Leg = "home-to-gig"
+	LegBetween  Leg = "gig-to-gig"
+	LegReturn   Leg = "gig-to-home"
+)
+
+func legOf(o atmos.Output) Leg {
+	switch {
+	case !o.From.IsZero() && !o.To.IsZero():
+		return LegBetween
+	case !o.To.IsZero():
+		return LegOutbound
+	case !o.From.IsZero():
+		return LegReturn
+	}
+	return ""
+}
+
+// Flight is one row of an artist's flight file, figures in the unit named by
+// the field.
+type Flight struct {
+	SchemaVersion     int     `json:"schema_version"`
+	ArtistID          string  `json:"artist_id"`
+	Artist            string  `json:"artist"`
+	Leg               Leg     `json:"leg_type"`
+	Date              string  `json:"date"`
+	Departure         string  `json:"departure"`
+	Arrival           string  `json:"arrival"`
+	Via               string  `json:"via"`
+	Passengers        int     `json:"passengers"`
+	CarbonKg          float64 `json:"carbon_kg"`
+	CarbonPerPersonKg float64 `json:"carbon_per_person_kg"`
+	FuelL             float64 `json:"fuel_l"`
+	DistanceKm        int     `json:"distance_km"`
+	OffsetEUR         float64 `json:"offset_eur"`
+	Tour              string  `json:"tour"`
+	FromDate          string  `json:"from_date"`
+	FromEvent         string  `json:"from_event"`
+	FromVenue         string  `json:"from_venue"`
+	ToDate            string  `json:"to_date"`
+	ToEvent           string  `json:"to_event"`
+	ToVenue           string  `json:"to_venue"`
+	Methodology       string  `json:"methodology"`
+}
+
+// Csv columns, named as the json fields.
+var FlightColumns = []string{"schema_version", "artist_id", "artist", "leg_type", "date", "departure", "arrival", "via", "passengers",
+	"carbon_kg", "carbon_per_person_kg", "fuel_l", "distance_km", "offset_eur", "tour",
+	"from_date", "from_event", "from_venue", "to_date", "to_event", "to_venue", "methodology"}
+
+// ArtistID is the artist's name as a lower case slug, e.g. "dj-koze" for
+// "DJ Koze", stable across runs and safe in file names and joins.
+func ArtistID(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+			continue
+		}
+		dash = true
+	}
+	return b.String()
+}
+
+func NewFlight(artist string, o atmos.Output) Flight {
+	f := Flight{
+		SchemaVersion:     SchemaVersion,
+		ArtistID:          ArtistID(artist),
+		Artist:            artist,
+		Leg:               legOf(o),
+		Date:              isoDate(o.FlightDay),
+		Departure:         o.DepartCode,
+		Arrival:           o.ArrivalCode,
+		Via:               o.Via,
+		Passengers:        o.Passengers,
+		CarbonKg:          o.CarbonOutput,
+		CarbonPerPersonKg: o.CarbonPerPerson(),
+		FuelL:             o.FuelInLiter,
+		DistanceKm:        o.Distance,
+		OffsetEUR:         o.OffsetEuros,
+		Tour:              o.Tour,
+		Methodology:       atmos.Methodology,
+	}
+	f.FromDate, f.FromEvent, f.FromVenue = gigFields(o.From)
+	f.ToDate, f.ToEvent, f.ToVenue = gigFields(o.To)
+	return f
+}
+
+// Output turns the row back into what atmos.Calculate gave, e.g. to attribute
+// it to its gigs.
+func (f Flight) Output() atmos.Output {
+	return atmos.Output{
+		DepartCode:   f.Departure,
+		ArrivalCode:  f.Arrival,
+		FlightDay:    f.Date,
+		OffsetEuros:  f.OffsetEUR,
+		CarbonOutput: f.CarbonKg,
+		FuelInLiter:  f.FuelL,
+		Distance:     f.DistanceKm,
+		Via:          f.Via,
+		Tour:         f.Tour,
+		Passengers:   f.Passengers,
+		From:         gigOf(f.FromDate, f.FromEvent, f.FromVenue),
+		To:           gigOf(f.ToDate, f.ToEvent, f.ToVenue),
+	}
+}
+
+func gigFields(gig flight.Gig) (string, string, string) {
+	if gig.IsZero() {
+		return "", "", ""
+	}
+	return gig.Date.Format("2006-01-02"), gig.Title, gig.Venue
+}
+
+func gigOf(date, title, venue string) flight.Gig {
+	d, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return flight.Gig{}
+	}
+	return flight.Gig{Date: d, Title: title, Venue: venue}
+}
+
+// Atmosfair echoes the date it was given, this guards against it changing.
+func isoDate(day string) string {
+	for _, layout := range []string{"2006-01-02", time.RFC3339, "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(layout, day); err == nil {
+			return t.Format("2006-01-02")
+		}
+	}
+	return day
+}
+
+func (f Flight) row() []string {
+	return []string{
+		strconv.Itoa(f.SchemaVersion),
+		f.ArtistID,
+		f.Artist,
+		string(f.Leg),
+		f.Date,
+		f.Departure,
+		f.Arrival,
+		f.Via,
+		strconv.Itoa(f.Passengers),
+		fmt.Sprintf("%f", f.CarbonKg),
+		fmt.Sprintf("%f", f.CarbonPerPersonKg),
+		fmt.Sprintf("%f", f.FuelL),
+		strconv.Itoa(f.DistanceKm),
+		fmt.Sprintf("%f", f.OffsetEUR),
+		f.Tour,
+		f.FromDate,
+		f.FromEvent,
+		f.FromVenue,
+		f.ToDate,
+		f.ToEvent,
+		f.ToVenue,
+		f.Methodology,
+	}
+}
+
+// IsJSONLines tells the json lines variant, one Flight object a line, from csv.
+func IsJSONLines(fName string) bool {
+	return strings.EqualFold(filepath.Ext(fName), ".jsonl")
+}
+
+// Write flight & carbon output data to artist file, json lines for a .jsonl
+// file name and csv otherwise.
+func WriteFlights(artist string, outputs []atmos.Output, fName string) error {
+	file, err := os.Create(fName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if IsJSONLines(fName) {
+		writer := bufio.NewWriter(file)
+		enc := json.NewEncoder(writer)
+		for _, o := range outputs {
+			if err := enc.Encode(NewFlight(artist, o)); err != nil {
+				return err
+			}
+		}
+		return writer.Flush()
+	}
+	csvwriter := csv.NewWriter(file)
+	csvwriter.Write(FlightColumns)
+	for _, o := range outputs {
+		csvwriter.Write(NewFlight(artist, o).row())
+	}
+	csvwriter.Flush()
+	return csvwriter.Error()
+}
+
+/*
+ReadFlights reads an artist's flight file in any schema version, the json
+lines variant included. Rows of a newer schema than this build knows are an
+error rather than being misread. Version 1 files carry no artist, it is
+taken from the file name.
+*/
+func ReadFlights(fName string) ([]Flight, error) {
+	file, err := os.Open(fName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if IsJSONLines(fName) {
+		return readJSONLines(fName, file)
+	}
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fName, err)
+	}
+	col := make(map[string]int)
+	for i, name := range header {
+		col[strings.TrimSpace(name)] = i
+	}
+	parse := parseRow
+	if _, ok := col["schema_version"]; !ok {
+		if _, ok := col["CARBON OUTPUT"]; !ok {
+			return nil, fmt.Errorf("%s: not a flight file, no schema_version or CARBON OUTPUT column", fName)
+		}
+		artist := strings.TrimSuffix(filepath.Base(fName), filepath.Ext(fName))
+		parse = func(get func(string) string) (Flight, error) {
+			return parseLegacyRow(artist, get), nil
+		}
+	}
+
+	var flights []Flight
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return flights, fmt.Errorf("%s: %v", fName, err)
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		f, err := parse(get)
+		if err != nil {
+			return flights, fmt.Errorf("%s:%d: %v", fName, line, err)
+		}
+		flights = append(flights, f)
+	}
+	return flights, nil
+}
+
+func readJSONLines(fName string, r io.Reader) ([]Flight, error) {
+	var flights []Flight
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var f Flight
+		if err := json.Unmarshal(scanner.Bytes(), &f); err != nil {
+			return flights, fmt.Errorf("%s:%d: %v", fName, line, err)
+		}
+		if err := checkVersion(f.SchemaVersion); err != nil {
+			return flights, fmt.Errorf("%s:%d: %v", fName, line, err)
+		}
+		flights = append(flights, f)
+	}
+	if err := scanner.Err(); err != nil {
+		return flights, fmt.Errorf("%s: %v", fName, err)
+	}
+	return flights, nil
+}
+
+func checkVersion(version int) error {
+	if version != SchemaVersion {
+		return fmt.Errorf("schema version %d, expected %d", version, SchemaVersion)
+	}
+	return nil
+}
+
+func parseRow(get func(string) string) (Flight, error) {
+	var (
+		f        Flight
+		firstErr error
+	)
+	integer := func(name string) int {
+		v := get(name)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", name, err)
+		}
+		return n
+	}
+	number := func(name string) float64 {
+		v := get(name)
+		if v == "" {
+			return 0
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("%s: %v", name, err)
+		}
+		return n
+	}
+	f.SchemaVersion = integer("schema_version")
+	if firstErr != nil {
+		return f, firstErr
+	}
+	if err := checkVersion(f.SchemaVersion); err != nil {
+		return f, err
+	}
+	f.ArtistID = get("artist_id")
+	f.Artist = get("artist")
+	f.Leg = Leg(get("leg_type"))
+	f.Date = get("date")
+	f.Departure = get("departure")
+	f.Arrival = get("arrival")
+	f.Via = get("via")
+	f.Passengers = integer("passengers")
+	f.CarbonKg = number("carbon_kg")
+	f.CarbonPerPersonKg = number("carbon_per_person_kg")
+	f.FuelL = number("fuel_l")
+	f.DistanceKm = integer("distance_km")
+	f.OffsetEUR = number("offset_eur")
+	f.Tour = get("tour")
+	f.FromDate = get("from_date")
+	f.FromEvent = get("from_event")
+	f.FromVenue = get("from_venue")
+	f.ToDate = get("to_date")
+	f.ToEvent = get("to_event")
+	f.ToVenue = get("to_venue")
+	f.Methodology = get("methodology")
+	return f, firstErr
+}
+
+// Version 1 rows, "€1.200000", "300.000000 kg", "120.000000 L" and "800 km".
+func parseLegacyRow(artist string, get func(string) string) Flight {
+	o := atmos.Output{
+		DepartCode:   get("DEPARTURE"),
+		ArrivalCode:  get("ARRIVAL"),
+		FlightDay:    get("DATE"),
+		OffsetEuros:  stripUnit(get("OFFSET"), "€"),
+		CarbonOutput: stripUnit(get("CARBON OUTPUT"), "kg"),
+		FuelInLiter:  stripUnit(get("FUEL"), "L"),
+		Distance:     int(stripUnit(get("DISTANCE"), "km")),
+		Via:          get("VIA"),
+		Tour:         get("TOUR"),
+		From:         gigOf(get("FROM DATE"), get("FROM EVENT"), get("FROM VENUE")),
+		To:           gigOf(get("TO DATE"), get("TO EVENT"), get("TO VENUE")),
+	}
+	o.Passengers, _ = strconv.Atoi(get("PASSENGERS"))
+	f := NewFlight(artist, o)
+	f.SchemaVersion = 1
+	if perPerson := get("CARBON PER PERSON"); perPerson != "" {
+		f.CarbonPerPersonKg = stripUnit(perPerson, "kg")
+	}
+	return f
+}
+
+func stripUnit(value, unit string) float64 {
+	value = strings.TrimSpace(strings.Replace(value, unit, "", -1))
+	f, _ := strconv.ParseFloat(value, 64)
+	return f
+}
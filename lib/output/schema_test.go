@@ -0,0 +1,171 @@
+package output
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/flight"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "output")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	fName := filepath.Join(tempDir(t), name)
+	if err := ioutil.WriteFile(fName, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fName
+}
+
+var berghain = flight.Gig{Date: time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC), Title: "Klubnacht", Venue: "Berghain"}
+
+var outputs = []atmos.Output{
+	{
+		DepartCode: "LHR", ArrivalCode: "TXL", FlightDay: "2019-03-01T15:00:00Z",
+		OffsetEuros: 12.5, CarbonOutput: 312.5, FuelInLiter: 120.25, Distance: 930,
+		Tour: "Europe tour 2019-03-01", Passengers: 2, To: berghain,
+	},
+	{
+		DepartCode: "TXL", ArrivalCode: "LHR", FlightDay: "2019-03-02",
+		OffsetEuros: 12.5, CarbonOutput: 312.5, FuelInLiter: 120.25, Distance: 930,
+		Passengers: 2, From: berghain,
+	},
+}
+
+// What WriteFlights writes ReadFlights reads back as written, in either format.
+func TestFlightsRoundTrip(t *testing.T) {
+	for _, name := range []string{"DJ Koze.csv", "DJ Koze.jsonl"} {
+		t.Run(name, func(t *testing.T) {
+			fName := filepath.Join(tempDir(t), name)
+			if err := WriteFlights("DJ Koze", outputs, fName); err != nil {
+				t.Fatal(err)
+			}
+			flights, err := ReadFlights(fName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(flights) != len(outputs) {
+				t.Fatalf("got %d flights, want %d", len(flights), len(outputs))
+			}
+			for i, f := range flights {
+				want := NewFlight("DJ Koze", outputs[i])
+				if !reflect.DeepEqual(f, want) {
+					t.Errorf("flight %d: got %+v, want %+v", i, f, want)
+				}
+			}
+			f := flights[0]
+			if f.SchemaVersion != SchemaVersion || f.ArtistID != "dj-koze" || f.Leg != LegOutbound || f.Date != "2019-03-01" || f.CarbonPerPersonKg != 156.25 {
+				t.Errorf("got %+v", f)
+			}
+			if flights[1].Leg != LegReturn {
+				t.Errorf("leg %q, want %q", flights[1].Leg, LegReturn)
+			}
+		})
+	}
+}
+
+func TestReadFlights(t *testing.T) {
+	header := strings.Join(FlightColumns, ",")
+	row := func(version string) string {
+		return version + ",tiga,Tiga,home-to-gig,2019-03-01,YUL,TXL,,1,500.5,500.5,200,6000,20,,,,,2019-03-01,Klubnacht,Berghain,atmosfair"
+	}
+	tests := []struct {
+		name    string
+		file    string
+		data    string
+		want    Flight
+		wantErr string
+	}{
+		{
+			name: "version 2",
+			file: "tiga.csv",
+			data: header + "\n" + row("2") + "\n",
+			want: Flight{
+				SchemaVersion: 2, ArtistID: "tiga", Artist: "Tiga", Leg: LegOutbound, Date: "2019-03-01",
+				Departure: "YUL", Arrival: "TXL", Passengers: 1, CarbonKg: 500.5, CarbonPerPersonKg: 500.5,
+				FuelL: 200, DistanceKm: 6000, OffsetEUR: 20, ToDate: "2019-03-01", ToEvent: "Klubnacht", ToVenue: "Berghain",
+				Methodology: "atmosfair",
+			},
+		},
+		{
+			name: "version 1 without a schema column",
+			file: "Tiga.csv",
+			data: "DEPARTURE,ARRIVAL,DATE,OFFSET,CARBON OUTPUT,FUEL,DISTANCE,VIA,TOUR,PASSENGERS,TO DATE,TO EVENT,TO VENUE\n" +
+				"YUL,TXL,2019-03-01,€20.000000,500.500000 kg,200.000000 L,6000 km,,,1,2019-03-01,Klubnacht,Berghain\n",
+			want: Flight{
+				SchemaVersion: 1, ArtistID: "tiga", Artist: "Tiga", Leg: LegOutbound, Date: "2019-03-01",
+				Departure: "YUL", Arrival: "TXL", Passengers: 1, CarbonKg: 500.5, CarbonPerPersonKg: 500.5,
+				FuelL: 200, DistanceKm: 6000, OffsetEUR: 20, ToDate: "2019-03-01", ToEvent: "Klubnacht", ToVenue: "Berghain",
+				Methodology: atmos.Methodology,
+			},
+		},
+		{
+			name:    "newer version",
+			file:    "tiga.csv",
+			data:    header + "\n" + row("2") + "\n" + row("3") + "\n",
+			wantErr: "tiga.csv:3: schema version 3, expected 2",
+		},
+		{
+			name:    "newer version in json lines",
+			file:    "tiga.jsonl",
+			data:    `{"schema_version": 2, "artist": "Tiga"}` + "\n" + `{"schema_version": 3, "artist": "Tiga"}` + "\n",
+			wantErr: "tiga.jsonl:2: schema version 3, expected 2",
+		},
+		{
+			name:    "unknown version",
+			file:    "tiga.csv",
+			data:    header + "\n" + row("v2") + "\n",
+			wantErr: "tiga.csv:2: schema_version:",
+		},
+		{
+			name:    "malformed figure",
+			file:    "tiga.csv",
+			data:    header + "\n" + row("2") + "\n" + strings.Replace(row("2"), "500.5,500.5", "lots,500.5", 1) + "\n",
+			wantErr: "tiga.csv:3: carbon_kg:",
+		},
+		{
+			name:    "malformed json line",
+			file:    "tiga.jsonl",
+			data:    `{"schema_version": 2}` + "\n\n" + `{"schema_version": 2,` + "\n",
+			wantErr: "tiga.jsonl:3:",
+		},
+		{
+			name:    "not a flight file",
+			file:    "tiga.csv",
+			data:    "name,city\nTiga,Montreal\n",
+			wantErr: "not a flight file",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flights, err := ReadFlights(writeFile(t, tt.file, tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(flights) != 1 || !reflect.DeepEqual(flights[0], tt.want) {
+				t.Errorf("got %+v, want %+v", flights, tt.want)
+			}
+		})
+	}
+}
@@ -1,27 +1,46 @@
 package ra
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
 	"strconv"
-	"strings"
 	"time"
 
 	"github.com/cleanscene.flights/lib/airports"
 	"github.com/cleanscene.flights/lib/crawler"
 	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/registry"
+	"github.com/cleanscene.flights/lib/source"
+	"github.com/cleanscene.flights/lib/venue"
 )
 
 type RA interface {
 	LoadArtists(string) (map[string]Artist, error)
-	LoadEvents(Artist) (crawler.Events, error)
+	LoadEvents(context.Context, Artist) (crawler.Events, error)
+	// ScrapeEvents lists the artist's gigs from each source, not yet geocoded.
+	ScrapeEvents(context.Context, Artist) ([]source.Listing, error)
+	// ResolveEvents geocodes scraped listings and merges them into one.
+	ResolveEvents(context.Context, []source.Listing) crawler.Events
 }
 
-func New(airSvc airports.Airports, crwlr crawler.Crawler, outputDir, tourYear string) RA {
+// New creates the service, the crawler doubling as the default event
+// source for artists whose registry entry does not pick any. Without a
+// crawler, e.g. reading gig files only, there is no default source unless
+// sources has one. Venues already resolved to an airport are taken from the
+// venue registry, which may be nil.
+func New(airSvc airports.Airports, crwlr crawler.Crawler, sources source.Set, venues venue.Registry, outputDir, tourYear string) RA {
+	var all = make(source.Set)
+	for name, src := range sources {
+		all[name] = src
+	}
+	if _, ok := all[source.Default]; !ok && crwlr != nil {
+		all[source.Default] = source.FromCrawler(crwlr)
+	}
 	return residentAdvisor{
 		airSvc:    airSvc,
 		crawler:   crwlr,
+		sources:   all,
+		venues:    venues,
 		outputDir: outputDir,
 		tourYear:  tourYear,
 	}
@@ -30,76 +49,227 @@ func New(airSvc airports.Airports, crwlr crawler.Crawler, outputDir, tourYear st
 type residentAdvisor struct {
 	airSvc    airports.Airports
 	crawler   crawler.Crawler
+	sources   source.Set
+	venues    venue.Registry
 	tourYear  string
 	outputDir string
 }
 
+// LoadArtists reads the artist registry, excluded artists are kept so they
+// can be accounted for but have no events loaded.
 func (ra residentAdvisor) LoadArtists(fileName string) (map[string]Artist, error) {
 	var artists = make(map[string]Artist)
 
-	file, err := os.Open(fileName)
+	entries, err := registry.Load(fileName)
 	if err != nil {
 		return artists, err
 	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		arr := strings.Split(scanner.Text(), ",")
-		eCount, _ := strconv.Atoi(arr[3])
-		link, _ := ra.crawler.GetArtistUrl(arr[0])
-		airCode, _ := ra.airSvc.AirCodeByCity(arr[1], arr[2])
-		artists[arr[0]] = Artist{
-			Name:        arr[0],
-			City:        arr[1],
-			Country:     arr[2],
-			EventsTotal: eCount,
-			Link:        link,
-			AirCode:     airCode,
+	for _, entry := range entries {
+		var link string
+		if ra.crawler != nil {
+			link, _ = ra.crawler.GetArtistUrl(entry.Name)
+			if entry.RASlug != "" {
+				link = ra.crawler.ArtistUrlFromSlug(entry.RASlug)
+			}
+		}
+		airCode := entry.HomeAirport
+		if airCode == "" && entry.City != "" {
+			airCode, _ = ra.airSvc.AirCodeByCity(entry.City, entry.Country)
+		}
+		artists[entry.Name] = Artist{
+			Name:           entry.Name,
+			City:           entry.City,
+			Country:        entry.Country,
+			EventsTotal:    entry.EventsTotal,
+			Link:           link,
+			SoundCloudUrl:  entry.SoundCloudUrl,
+			AirCode:        airCode,
+			HometownSource: entry.HometownSource,
+			Members:        entry.Members,
+			Crew:           entry.Crew,
+			Excluded:       entry.Excluded,
+			Sources:        entry.Sources,
 			// initialise with empty events map
-			Events: make(map[time.Time]event.Event),
+			Events: make(Events),
 		}
-
-	}
-
-	if err := scanner.Err(); err != nil {
-		return artists, err
 	}
 	return artists, nil
 
 }
 
 type Artist struct {
-	Name        string
-	City        string
-	Country     string
-	EventsTotal int
-	Link        string
-	AirCode     string
-	Events      Events
+	Name           string
+	City           string
+	Country        string
+	HometownSource registry.Source
+	EventsTotal    int
+	Link           string
+	SoundCloudUrl  string
+	AirCode        string
+	// People in the act and crew travelling with them, all flying every leg.
+	Members int
+	Crew    int
+	// Set for artists left out of the study.
+	Excluded registry.Reason
+	// Event sources to load gigs from, in order of precedence.
+	Sources []string
+	Events  Events
+}
+
+// Passengers is everyone flying for a gig, at least the artist themselves.
+func (a Artist) Passengers() int {
+	if a.Members+a.Crew < 1 {
+		return 1
+	}
+	return a.Members + a.Crew
 }
 
-type Events map[time.Time]event.Event
+type Events = crawler.Events
 
-func (ra residentAdvisor) getEventAirports(events crawler.Events) crawler.Events {
-	for d, e := range events {
-		edge, err := ra.airSvc.FindClosestAirport(e.Location)
+func (ra residentAdvisor) getEventAirports(ctx context.Context, events crawler.Events) crawler.Events {
+	for _, day := range events {
+		for i, e := range day {
+			day[i] = ra.getEventAirport(ctx, e)
+		}
+	}
+	return events
+}
+
+func (ra residentAdvisor) getEventAirport(ctx context.Context, e event.Event) event.Event {
+	var (
+		edge airports.Edge
+		err  error
+	)
+	if v, ok := ra.knownVenue(e.VenueID); ok {
+		return fromVenue(e, v)
+	}
+	if e.Lat == 0 && e.Lng == 0 {
+		e.Lng, e.Lat, err = ra.airSvc.Geocode(ctx, e.Location)
 		if err != nil {
 			fmt.Println(err.Error())
+			e.Failure = fmt.Sprintf("geocoding %q: %v", e.Location, err)
+			return e
+		}
+	}
+	edge, err = ra.airSvc.FindClosestAirportByCoords(ctx, e.Lng, e.Lat)
+	if err != nil {
+		fmt.Println(err.Error())
+		e.Failure = fmt.Sprintf("nearest airport to %f,%f: %v", e.Lat, e.Lng, err)
+		return e
+	}
+	e.AirCode = edge.Code
+	e.City = edge.CityCode
+	e.Country = edge.Country
+	e.CountryCode = edge.CountryCode
+	e.TimeZone = edge.Timezone
+	if ra.venues != nil && e.VenueID != "" {
+		ra.venues.Put(venue.Venue{
+			ID:          e.VenueID,
+			Name:        e.Venue,
+			Address:     e.Location,
+			Lat:         e.Lat,
+			Lng:         e.Lng,
+			AirCode:     e.AirCode,
+			City:        e.City,
+			Country:     e.Country,
+			CountryCode: e.CountryCode,
+			TimeZone:    e.TimeZone,
+			Source:      venue.Geocoded,
+		})
+	}
+	return e
+}
+
+func (ra residentAdvisor) knownVenue(id string) (venue.Venue, bool) {
+	if ra.venues == nil || id == "" {
+		return venue.Venue{}, false
+	}
+	v, ok := ra.venues.Get(id)
+	return v, ok && v.AirCode != ""
+}
+
+func fromVenue(e event.Event, v venue.Venue) event.Event {
+	if v.Address != "" {
+		e.Location = v.Address
+	}
+	if v.Name != "" {
+		e.Venue = v.Name
+	}
+	e.Lat, e.Lng = v.Lat, v.Lng
+	e.AirCode = v.AirCode
+	e.City = v.City
+	e.Country = v.Country
+	e.CountryCode = v.CountryCode
+	e.TimeZone = v.TimeZone
+	return e
+}
+
+// LoadEvents merges the artist's gigs in the tour year from each of their
+// sources, see source.Dedupe. A source failing is only an error when none
+// of them succeed.
+func (ra residentAdvisor) LoadEvents(ctx context.Context, a Artist) (crawler.Events, error) {
+	listings, err := ra.ScrapeEvents(ctx, a)
+	if err != nil {
+		return make(crawler.Events), err
+	}
+	return ra.ResolveEvents(ctx, listings), nil
+}
+
+func (ra residentAdvisor) ScrapeEvents(ctx context.Context, a Artist) ([]source.Listing, error) {
+	specs := a.Sources
+	if len(specs) == 0 {
+		specs = []string{source.Default}
+	}
+	from, to := ra.tourRange()
+	var (
+		listings []source.Listing
+		lastErr  error
+	)
+	for _, spec := range specs {
+		events, err := ra.loadFrom(ctx, spec, a, from, to)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %v", spec, err)
+			fmt.Println(lastErr.Error())
 			continue
 		}
-		e.AirCode = edge.Code
-		e.City = edge.CityCode
-		e.Country = edge.Country
-		events[d] = e
+		listings = append(listings, source.Listing{Source: spec, Events: events})
 	}
-	return events
+	if len(listings) == 0 {
+		return listings, lastErr
+	}
+	return listings, nil
+}
+
+// ResolveEvents finds the airport of every gig, then merges the listings, see
+// source.Dedupe.
+func (ra residentAdvisor) ResolveEvents(ctx context.Context, listings []source.Listing) crawler.Events {
+	for i := range listings {
+		listings[i].Events = ra.getEventAirports(ctx, listings[i].Events)
+	}
+	return source.Dedupe(listings)
+}
+
+func (ra residentAdvisor) loadFrom(ctx context.Context, spec string, a Artist, from, to time.Time) (crawler.Events, error) {
+	src, ref, err := ra.sources.Resolve(spec)
+	if err != nil {
+		return nil, err
+	}
+	if ref == "" && spec == source.Default {
+		ref = a.Link
+	}
+	if ref == "" {
+		if ref, err = src.FindArtist(a.Name); err != nil {
+			return nil, err
+		}
+	}
+	return src.Events(ctx, ref, from, to)
 }
 
-func (ra residentAdvisor) LoadEvents(a Artist) (crawler.Events, error) {
-	events, err := ra.crawler.GetArtistEvents(a.Link, ra.tourYear)
+func (ra residentAdvisor) tourRange() (time.Time, time.Time) {
+	year, err := strconv.Atoi(ra.tourYear)
 	if err != nil {
-		return events, err
+		return time.Time{}, time.Time{}
 	}
-	return ra.getEventAirports(events), nil
+	from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(1, 0, 0).Add(-time.Nanosecond)
 }
@@ -0,0 +1,19 @@
+package ra
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/cleanscene.flights/lib/source"
+)
+
+// Reading gig files only there is no crawler, artists without sources of
+// their own cannot be looked up on RA.
+func TestScrapeWithoutCrawler(t *testing.T) {
+	raSvc := New(nil, nil, source.Set{}, nil, "", "2019")
+	_, err := raSvc.ScrapeEvents(context.Background(), Artist{Name: "Tiga"})
+	if err == nil || !strings.Contains(err.Error(), "source ra not configured") {
+		t.Errorf("got %v, want source ra not configured", err)
+	}
+}
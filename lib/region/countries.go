@@ -0,0 +1,376 @@
+package region
+
+// UN M49 subregions.
+const (
+	NorthernAfrica      = "Northern Africa"
+	EasternAfrica       = "Eastern Africa"
+	MiddleAfrica        = "Middle Africa"
+	SouthernAfrica      = "Southern Africa"
+	WesternAfrica       = "Western Africa"
+	Caribbean           = "Caribbean"
+	CentralAmerica      = "Central America"
+	SouthAmericaSub     = "South America"
+	NorthernAmerica     = "Northern America"
+	CentralAsia         = "Central Asia"
+	EasternAsia         = "Eastern Asia"
+	SouthEasternAsia    = "South-eastern Asia"
+	SouthernAsia        = "Southern Asia"
+	WesternAsia         = "Western Asia"
+	EasternEurope       = "Eastern Europe"
+	NorthernEurope      = "Northern Europe"
+	SouthernEurope      = "Southern Europe"
+	WesternEurope       = "Western Europe"
+	AustraliaNewZealand = "Australia and New Zealand"
+	Melanesia           = "Melanesia"
+	Micronesia          = "Micronesia"
+	Polynesia           = "Polynesia"
+	AntarcticaSub       = "Antarctica"
+)
+
+/*
+Countries follow the UN M49 geoscheme. The UN "Americas" region is split into
+North America (Northern America, Central America and the Caribbean) and South
+America. Transcontinental countries sit where M49 puts them: Russia in
+Eastern Europe, Turkey, Cyprus and the Caucasus in Western Asia, Egypt in
+Northern Africa. Overseas territories belong to the region they are in, not
+to their sovereign state (Réunion is Eastern Africa, Martinique Caribbean).
+*/
+var countries = []Region{
+	// Africa
+	{"DZ", "Algeria", Africa, NorthernAfrica},
+	{"EG", "Egypt", Africa, NorthernAfrica},
+	{"LY", "Libya", Africa, NorthernAfrica},
+	{"MA", "Morocco", Africa, NorthernAfrica},
+	{"SD", "Sudan", Africa, NorthernAfrica},
+	{"TN", "Tunisia", Africa, NorthernAfrica},
+	{"EH", "Western Sahara", Africa, NorthernAfrica},
+	{"IO", "British Indian Ocean Territory", Africa, EasternAfrica},
+	{"BI", "Burundi", Africa, EasternAfrica},
+	{"KM", "Comoros", Africa, EasternAfrica},
+	{"DJ", "Djibouti", Africa, EasternAfrica},
+	{"ER", "Eritrea", Africa, EasternAfrica},
+	{"ET", "Ethiopia", Africa, EasternAfrica},
+	{"TF", "French Southern Territories", Africa, EasternAfrica},
+	{"KE", "Kenya", Africa, EasternAfrica},
+	{"MG", "Madagascar", Africa, EasternAfrica},
+	{"MW", "Malawi", Africa, EasternAfrica},
+	{"MU", "Mauritius", Africa, EasternAfrica},
+	{"YT", "Mayotte", Africa, EasternAfrica},
+	{"MZ", "Mozambique", Africa, EasternAfrica},
+	{"RE", "Réunion", Africa, EasternAfrica},
+	{"RW", "Rwanda", Africa, EasternAfrica},
+	{"SC", "Seychelles", Africa, EasternAfrica},
+	{"SO", "Somalia", Africa, EasternAfrica},
+	{"SS", "South Sudan", Africa, EasternAfrica},
+	{"UG", "Uganda", Africa, EasternAfrica},
+	{"TZ", "Tanzania", Africa, EasternAfrica},
+	{"ZM", "Zambia", Africa, EasternAfrica},
+	{"ZW", "Zimbabwe", Africa, EasternAfrica},
+	{"AO", "Angola", Africa, MiddleAfrica},
+	{"CM", "Cameroon", Africa, MiddleAfrica},
+	{"CF", "Central African Republic", Africa, MiddleAfrica},
+	{"TD", "Chad", Africa, MiddleAfrica},
+	{"CG", "Congo", Africa, MiddleAfrica},
+	{"CD", "Democratic Republic of the Congo", Africa, MiddleAfrica},
+	{"GQ", "Equatorial Guinea", Africa, MiddleAfrica},
+	{"GA", "Gabon", Africa, MiddleAfrica},
+	{"ST", "Sao Tome and Principe", Africa, MiddleAfrica},
+	{"BW", "Botswana", Africa, SouthernAfrica},
+	{"SZ", "Eswatini", Africa, SouthernAfrica},
+	{"LS", "Lesotho", Africa, SouthernAfrica},
+	{"NA", "Namibia", Africa, SouthernAfrica},
+	{"ZA", "South Africa", Africa, SouthernAfrica},
+	{"BJ", "Benin", Africa, WesternAfrica},
+	{"BF", "Burkina Faso", Africa, WesternAfrica},
+	{"CV", "Cabo Verde", Africa, WesternAfrica},
+	{"CI", "Côte d'Ivoire", Africa, WesternAfrica},
+	{"GM", "Gambia", Africa, WesternAfrica},
+	{"GH", "Ghana", Africa, WesternAfrica},
+	{"GN", "Guinea", Africa, WesternAfrica},
+	{"GW", "Guinea-Bissau", Africa, WesternAfrica},
+	{"LR", "Liberia", Africa, WesternAfrica},
+	{"ML", "Mali", Africa, WesternAfrica},
+	{"MR", "Mauritania", Africa, WesternAfrica},
+	{"NE", "Niger", Africa, WesternAfrica},
+	{"NG", "Nigeria", Africa, WesternAfrica},
+	{"SH", "Saint Helena", Africa, WesternAfrica},
+	{"SN", "Senegal", Africa, WesternAfrica},
+	{"SL", "Sierra Leone", Africa, WesternAfrica},
+	{"TG", "Togo", Africa, WesternAfrica},
+
+	// North America
+	{"AI", "Anguilla", NorthAmerica, Caribbean},
+	{"AG", "Antigua and Barbuda", NorthAmerica, Caribbean},
+	{"AW", "Aruba", NorthAmerica, Caribbean},
+	{"BS", "Bahamas", NorthAmerica, Caribbean},
+	{"BB", "Barbados", NorthAmerica, Caribbean},
+	{"BQ", "Bonaire, Sint Eustatius and Saba", NorthAmerica, Caribbean},
+	{"VG", "British Virgin Islands", NorthAmerica, Caribbean},
+	{"KY", "Cayman Islands", NorthAmerica, Caribbean},
+	{"CU", "Cuba", NorthAmerica, Caribbean},
+	{"CW", "Curaçao", NorthAmerica, Caribbean},
+	{"DM", "Dominica", NorthAmerica, Caribbean},
+	{"DO", "Dominican Republic", NorthAmerica, Caribbean},
+	{"GD", "Grenada", NorthAmerica, Caribbean},
+	{"GP", "Guadeloupe", NorthAmerica, Caribbean},
+	{"HT", "Haiti", NorthAmerica, Caribbean},
+	{"JM", "Jamaica", NorthAmerica, Caribbean},
+	{"MQ", "Martinique", NorthAmerica, Caribbean},
+	{"MS", "Montserrat", NorthAmerica, Caribbean},
+	{"PR", "Puerto Rico", NorthAmerica, Caribbean},
+	{"BL", "Saint Barthélemy", NorthAmerica, Caribbean},
+	{"KN", "Saint Kitts and Nevis", NorthAmerica, Caribbean},
+	{"LC", "Saint Lucia", NorthAmerica, Caribbean},
+	{"MF", "Saint Martin", NorthAmerica, Caribbean},
+	{"VC", "Saint Vincent and the Grenadines", NorthAmerica, Caribbean},
+	{"SX", "Sint Maarten", NorthAmerica, Caribbean},
+	{"TT", "Trinidad and Tobago", NorthAmerica, Caribbean},
+	{"TC", "Turks and Caicos Islands", NorthAmerica, Caribbean},
+	{"VI", "United States Virgin Islands", NorthAmerica, Caribbean},
+	{"BZ", "Belize", NorthAmerica, CentralAmerica},
+	{"CR", "Costa Rica", NorthAmerica, CentralAmerica},
+	{"SV", "El Salvador", NorthAmerica, CentralAmerica},
+	{"GT", "Guatemala", NorthAmerica, CentralAmerica},
+	{"HN", "Honduras", NorthAmerica, CentralAmerica},
+	{"MX", "Mexico", NorthAmerica, CentralAmerica},
+	{"NI", "Nicaragua", NorthAmerica, CentralAmerica},
+	{"PA", "Panama", NorthAmerica, CentralAmerica},
+	{"BM", "Bermuda", NorthAmerica, NorthernAmerica},
+	{"CA", "Canada", NorthAmerica, NorthernAmerica},
+	{"GL", "Greenland", NorthAmerica, NorthernAmerica},
+	{"PM", "Saint Pierre and Miquelon", NorthAmerica, NorthernAmerica},
+	{"US", "United States", NorthAmerica, NorthernAmerica},
+
+	// South America
+	{"AR", "Argentina", SouthAmerica, SouthAmericaSub},
+	{"BO", "Bolivia", SouthAmerica, SouthAmericaSub},
+	{"BV", "Bouvet Island", SouthAmerica, SouthAmericaSub},
+	{"BR", "Brazil", SouthAmerica, SouthAmericaSub},
+	{"CL", "Chile", SouthAmerica, SouthAmericaSub},
+	{"CO", "Colombia", SouthAmerica, SouthAmericaSub},
+	{"EC", "Ecuador", SouthAmerica, SouthAmericaSub},
+	{"FK", "Falkland Islands", SouthAmerica, SouthAmericaSub},
+	{"GF", "French Guiana", SouthAmerica, SouthAmericaSub},
+	{"GY", "Guyana", SouthAmerica, SouthAmericaSub},
+	{"PY", "Paraguay", SouthAmerica, SouthAmericaSub},
+	{"PE", "Peru", SouthAmerica, SouthAmericaSub},
+	{"GS", "South Georgia and the South Sandwich Islands", SouthAmerica, SouthAmericaSub},
+	{"SR", "Suriname", SouthAmerica, SouthAmericaSub},
+	{"UY", "Uruguay", SouthAmerica, SouthAmericaSub},
+	{"VE", "Venezuela", SouthAmerica, SouthAmericaSub},
+
+	// Asia
+	{"KZ", "Kazakhstan", Asia, CentralAsia},
+	{"KG", "Kyrgyzstan", Asia, CentralAsia},
+	{"TJ", "Tajikistan", Asia, CentralAsia},
+	{"TM", "Turkmenistan", Asia, CentralAsia},
+	{"UZ", "Uzbekistan", Asia, CentralAsia},
+	{"CN", "China", Asia, EasternAsia},
+	{"HK", "Hong Kong", Asia, EasternAsia},
+	{"MO", "Macao", Asia, EasternAsia},
+	{"KP", "North Korea", Asia, EasternAsia},
+	{"JP", "Japan", Asia, EasternAsia},
+	{"MN", "Mongolia", Asia, EasternAsia},
+	{"KR", "South Korea", Asia, EasternAsia},
+	{"TW", "Taiwan", Asia, EasternAsia},
+	{"BN", "Brunei", Asia, SouthEasternAsia},
+	{"KH", "Cambodia", Asia, SouthEasternAsia},
+	{"ID", "Indonesia", Asia, SouthEasternAsia},
+	{"LA", "Laos", Asia, SouthEasternAsia},
+	{"MY", "Malaysia", Asia, SouthEasternAsia},
+	{"MM", "Myanmar", Asia, SouthEasternAsia},
+	{"PH", "Philippines", Asia, SouthEasternAsia},
+	{"SG", "Singapore", Asia, SouthEasternAsia},
+	{"TH", "Thailand", Asia, SouthEasternAsia},
+	{"TL", "Timor-Leste", Asia, SouthEasternAsia},
+	{"VN", "Vietnam", Asia, SouthEasternAsia},
+	{"AF", "Afghanistan", Asia, SouthernAsia},
+	{"BD", "Bangladesh", Asia, SouthernAsia},
+	{"BT", "Bhutan", Asia, SouthernAsia},
+	{"IN", "India", Asia, SouthernAsia},
+	{"IR", "Iran", Asia, SouthernAsia},
+	{"MV", "Maldives", Asia, SouthernAsia},
+	{"NP", "Nepal", Asia, SouthernAsia},
+	{"PK", "Pakistan", Asia, SouthernAsia},
+	{"LK", "Sri Lanka", Asia, SouthernAsia},
+	{"AM", "Armenia", Asia, WesternAsia},
+	{"AZ", "Azerbaijan", Asia, WesternAsia},
+	{"BH", "Bahrain", Asia, WesternAsia},
+	{"CY", "Cyprus", Asia, WesternAsia},
+	{"GE", "Georgia", Asia, WesternAsia},
+	{"IQ", "Iraq", Asia, WesternAsia},
+	{"IL", "Israel", Asia, WesternAsia},
+	{"JO", "Jordan", Asia, WesternAsia},
+	{"KW", "Kuwait", Asia, WesternAsia},
+	{"LB", "Lebanon", Asia, WesternAsia},
+	{"OM", "Oman", Asia, WesternAsia},
+	{"PS", "Palestine", Asia, WesternAsia},
+	{"QA", "Qatar", Asia, WesternAsia},
+	{"SA", "Saudi Arabia", Asia, WesternAsia},
+	{"SY", "Syria", Asia, WesternAsia},
+	{"TR", "Turkey", Asia, WesternAsia},
+	{"AE", "United Arab Emirates", Asia, WesternAsia},
+	{"YE", "Yemen", Asia, WesternAsia},
+
+	// Europe
+	{"BY", "Belarus", Europe, EasternEurope},
+	{"BG", "Bulgaria", Europe, EasternEurope},
+	{"CZ", "Czechia", Europe, EasternEurope},
+	{"HU", "Hungary", Europe, EasternEurope},
+	{"MD", "Moldova", Europe, EasternEurope},
+	{"PL", "Poland", Europe, EasternEurope},
+	{"RO", "Romania", Europe, EasternEurope},
+	{"RU", "Russia", Europe, EasternEurope},
+	{"SK", "Slovakia", Europe, EasternEurope},
+	{"UA", "Ukraine", Europe, EasternEurope},
+	{"AX", "Åland Islands", Europe, NorthernEurope},
+	{"DK", "Denmark", Europe, NorthernEurope},
+	{"EE", "Estonia", Europe, NorthernEurope},
+	{"FO", "Faroe Islands", Europe, NorthernEurope},
+	{"FI", "Finland", Europe, NorthernEurope},
+	{"GG", "Guernsey", Europe, NorthernEurope},
+	{"IS", "Iceland", Europe, NorthernEurope},
+	{"IE", "Ireland", Europe, NorthernEurope},
+	{"IM", "Isle of Man", Europe, NorthernEurope},
+	{"JE", "Jersey", Europe, NorthernEurope},
+	{"LV", "Latvia", Europe, NorthernEurope},
+	{"LT", "Lithuania", Europe, NorthernEurope},
+	{"NO", "Norway", Europe, NorthernEurope},
+	{"SJ", "Svalbard and Jan Mayen", Europe, NorthernEurope},
+	{"SE", "Sweden", Europe, NorthernEurope},
+	{"GB", "United Kingdom", Europe, NorthernEurope},
+	{"AL", "Albania", Europe, SouthernEurope},
+	{"AD", "Andorra", Europe, SouthernEurope},
+	{"BA", "Bosnia and Herzegovina", Europe, SouthernEurope},
+	{"HR", "Croatia", Europe, SouthernEurope},
+	{"GI", "Gibraltar", Europe, SouthernEurope},
+	{"GR", "Greece", Europe, SouthernEurope},
+	{"VA", "Holy See", Europe, SouthernEurope},
+	{"IT", "Italy", Europe, SouthernEurope},
+	{"XK", "Kosovo", Europe, SouthernEurope},
+	{"MT", "Malta", Europe, SouthernEurope},
+	{"ME", "Montenegro", Europe, SouthernEurope},
+	{"MK", "North Macedonia", Europe, SouthernEurope},
+	{"PT", "Portugal", Europe, SouthernEurope},
+	{"SM", "San Marino", Europe, SouthernEurope},
+	{"RS", "Serbia", Europe, SouthernEurope},
+	{"SI", "Slovenia", Europe, SouthernEurope},
+	{"ES", "Spain", Europe, SouthernEurope},
+	{"AT", "Austria", Europe, WesternEurope},
+	{"BE", "Belgium", Europe, WesternEurope},
+	{"FR", "France", Europe, WesternEurope},
+	{"DE", "Germany", Europe, WesternEurope},
+	{"LI", "Liechtenstein", Europe, WesternEurope},
+	{"LU", "Luxembourg", Europe, WesternEurope},
+	{"MC", "Monaco", Europe, WesternEurope},
+	{"NL", "Netherlands", Europe, WesternEurope},
+	{"CH", "Switzerland", Europe, WesternEurope},
+
+	// Oceania
+	{"AU", "Australia", Oceania, AustraliaNewZealand},
+	{"CX", "Christmas Island", Oceania, AustraliaNewZealand},
+	{"CC", "Cocos (Keeling) Islands", Oceania, AustraliaNewZealand},
+	{"HM", "Heard Island and McDonald Islands", Oceania, AustraliaNewZealand},
+	{"NZ", "New Zealand", Oceania, AustraliaNewZealand},
+	{"NF", "Norfolk Island", Oceania, AustraliaNewZealand},
+	{"FJ", "Fiji", Oceania, Melanesia},
+	{"NC", "New Caledonia", Oceania, Melanesia},
+	{"PG", "Papua New Guinea", Oceania, Melanesia},
+	{"SB", "Solomon Islands", Oceania, Melanesia},
+	{"VU", "Vanuatu", Oceania, Melanesia},
+	{"GU", "Guam", Oceania, Micronesia},
+	{"KI", "Kiribati", Oceania, Micronesia},
+	{"MH", "Marshall Islands", Oceania, Micronesia},
+	{"FM", "Micronesia", Oceania, Micronesia},
+	{"NR", "Nauru", Oceania, Micronesia},
+	{"MP", "Northern Mariana Islands", Oceania, Micronesia},
+	{"PW", "Palau", Oceania, Micronesia},
+	{"UM", "United States Minor Outlying Islands", Oceania, Micronesia},
+	{"AS", "American Samoa", Oceania, Polynesia},
+	{"CK", "Cook Islands", Oceania, Polynesia},
+	{"PF", "French Polynesia", Oceania, Polynesia},
+	{"NU", "Niue", Oceania, Polynesia},
+	{"PN", "Pitcairn", Oceania, Polynesia},
+	{"WS", "Samoa", Oceania, Polynesia},
+	{"TK", "Tokelau", Oceania, Polynesia},
+	{"TO", "Tonga", Oceania, Polynesia},
+	{"TV", "Tuvalu", Oceania, Polynesia},
+	{"WF", "Wallis and Futuna", Oceania, Polynesia},
+
+	{"AQ", "Antarctica", Antarctica, AntarcticaSub},
+}
+
+// Alternative spellings seen in the artist list, RA, aviation-edge and the
+// country-mapper data, resolved to ISO-3166 alpha-2 codes.
+var aliases = map[string]string{
+	"USA":                      "US",
+	"United States of America": "US",
+	"America":                  "US",
+	"UK":                       "GB",
+	"Great Britain":            "GB",
+	"Britain":                  "GB",
+	"England":                  "GB",
+	"Scotland":                 "GB",
+	"Wales":                    "GB",
+	"Northern Ireland":         "GB",
+	"United Kingdom of Great Britain and Northern Ireland": "GB",
+	"Russian Federation":                     "RU",
+	"Türkiye":                                "TR",
+	"Turkiye":                                "TR",
+	"Czech Republic":                         "CZ",
+	"Holland":                                "NL",
+	"Netherlands Antilles":                   "CW",
+	"Korea":                                  "KR",
+	"Korea, Republic of":                     "KR",
+	"Republic of Korea":                      "KR",
+	"Korea, Democratic People's Republic of": "KP",
+	"Taiwan, Province of China":              "TW",
+	"Republic of China":                      "TW",
+	"Hong Kong SAR":                          "HK",
+	"Hong Kong SAR China":                    "HK",
+	"Macau":                                  "MO",
+	"Macao SAR China":                        "MO",
+	"Viet Nam":                               "VN",
+	"Lao People's Democratic Republic":       "LA",
+	"Burma":                                  "MM",
+	"Iran, Islamic Republic of":              "IR",
+	"Syrian Arab Republic":                   "SY",
+	"Palestinian Territory":                  "PS",
+	"Palestine, State of":                    "PS",
+	"UAE":                                    "AE",
+	"Macedonia":                              "MK",
+	"Macedonia, the former Yugoslav Republic of":   "MK",
+	"Republic of North Macedonia":                  "MK",
+	"Bosnia":                                       "BA",
+	"Moldova, Republic of":                         "MD",
+	"Vatican":                                      "VA",
+	"Vatican City":                                 "VA",
+	"Ivory Coast":                                  "CI",
+	"Cape Verde":                                   "CV",
+	"Swaziland":                                    "SZ",
+	"Tanzania, United Republic of":                 "TZ",
+	"Congo, The Democratic Republic of the":        "CD",
+	"DR Congo":                                     "CD",
+	"Republic of the Congo":                        "CG",
+	"Libyan Arab Jamahiriya":                       "LY",
+	"East Timor":                                   "TL",
+	"Brunei Darussalam":                            "BN",
+	"Bolivia, Plurinational State of":              "BO",
+	"Venezuela, Bolivarian Republic of":            "VE",
+	"Falkland Islands (Malvinas)":                  "FK",
+	"Micronesia, Federated States of":              "FM",
+	"Saint Martin (French part)":                   "MF",
+	"Sint Maarten (Dutch part)":                    "SX",
+	"Virgin Islands, British":                      "VG",
+	"Virgin Islands, U.S.":                         "VI",
+	"US Virgin Islands":                            "VI",
+	"St Lucia":                                     "LC",
+	"St Kitts and Nevis":                           "KN",
+	"St Vincent and the Grenadines":                "VC",
+	"St Barts":                                     "BL",
+	"Saint Helena, Ascension and Tristan da Cunha": "SH",
+	"Reunion":                                      "RE",
+	"Curacao":                                      "CW",
+	"Ibiza":                                        "ES",
+	"Canary Islands":                               "ES",
+}
@@ -0,0 +1,118 @@
+package region
+
+import (
+	"strings"
+	"unicode"
+)
+
+type Continent string
+
+const (
+	Africa       Continent = "Africa"
+	Antarctica   Continent = "Antarctica"
+	Asia         Continent = "Asia"
+	Europe       Continent = "Europe"
+	NorthAmerica Continent = "North America"
+	Oceania      Continent = "Oceania"
+	SouthAmerica Continent = "South America"
+)
+
+// A Region describes where a country sits, keyed by its ISO-3166 alpha-2 code.
+type Region struct {
+	Code      string
+	Name      string
+	Continent Continent
+	Subregion string
+}
+
+type Regions interface {
+	Lookup(string) (Region, bool)
+	SameContinent(string, string) bool
+	SameForeignContinent(string, string, string) bool
+}
+
+func New() Regions {
+	svc := service{
+		byCode:  make(map[string]Region),
+		byAlias: make(map[string]string),
+	}
+	for _, r := range countries {
+		svc.byCode[r.Code] = r
+		svc.byAlias[normalize(r.Name)] = r.Code
+	}
+	for alias, code := range aliases {
+		svc.byAlias[normalize(alias)] = code
+	}
+	return svc
+}
+
+type service struct {
+	byCode  map[string]Region
+	byAlias map[string]string
+}
+
+// Lookup resolves an ISO-3166 alpha-2 code, a country name or one of its
+// common spellings (e.g. aviation-edge's "Russian Federation") to a Region.
+func (s service) Lookup(country string) (Region, bool) {
+	country = strings.TrimSpace(country)
+	if country == "" {
+		return Region{}, false
+	}
+	if len(country) == 2 {
+		if r, ok := s.byCode[strings.ToUpper(country)]; ok {
+			return r, true
+		}
+	}
+	code, ok := s.byAlias[normalize(country)]
+	if !ok {
+		return Region{}, false
+	}
+	return s.byCode[code], true
+}
+
+func (s service) SameContinent(c1, c2 string) bool {
+	r1, ok1 := s.Lookup(c1)
+	r2, ok2 := s.Lookup(c2)
+	return ok1 && ok2 && r1.Continent == r2.Continent
+}
+
+// SameForeignContinent reports whether both gig countries are on the same
+// continent and that continent is not the one the artist is based on.
+// Unknown countries never match, so the planner falls back to flying home.
+func (s service) SameForeignContinent(c1, c2, home string) bool {
+	r1, ok1 := s.Lookup(c1)
+	r2, ok2 := s.Lookup(c2)
+	h, okh := s.Lookup(home)
+	if !ok1 || !ok2 || !okh {
+		return false
+	}
+	return r1.Continent == r2.Continent && r1.Continent != h.Continent
+}
+
+var accents = strings.NewReplacer(
+	"á", "a", "à", "a", "â", "a", "ä", "a", "ã", "a", "å", "a",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"í", "i", "ì", "i", "î", "i", "ï", "i",
+	"ó", "o", "ò", "o", "ô", "o", "ö", "o", "õ", "o", "ø", "o",
+	"ú", "u", "ù", "u", "û", "u", "ü", "u",
+	"ç", "c", "ñ", "n", "ý", "y", "ß", "ss", "&", " and ",
+)
+
+// Fold puts a name in the form names are compared in, lower case, without
+// accents and with punctuation as single spaces, e.g. "côte d ivoire".
+func Fold(name string) string {
+	name = accents.Replace(strings.ToLower(name))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return ' '
+	}, name)
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// Countries are also looked up without a leading "the", so "the
+// Netherlands" resolves.
+func normalize(name string) string {
+	return strings.TrimPrefix(Fold(name), "the ")
+}
@@ -0,0 +1,114 @@
+package region
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+	regions := New()
+	tests := []struct {
+		in        string
+		code      string
+		continent Continent
+		subregion string
+	}{
+		{"Turkey", "TR", Asia, WesternAsia},
+		{"Türkiye", "TR", Asia, WesternAsia},
+		{"TR", "TR", Asia, WesternAsia},
+		{"tr", "TR", Asia, WesternAsia},
+		{"Russia", "RU", Europe, EasternEurope},
+		{"Russian Federation", "RU", Europe, EasternEurope},
+		{"RU", "RU", Europe, EasternEurope},
+		{"PR", "PR", NorthAmerica, Caribbean},
+		{"Puerto Rico", "PR", NorthAmerica, Caribbean},
+		{"TT", "TT", NorthAmerica, Caribbean},
+		{"Trinidad & Tobago", "TT", NorthAmerica, Caribbean},
+		{"CW", "CW", NorthAmerica, Caribbean},
+		{"Curacao", "CW", NorthAmerica, Caribbean},
+		{"Curaçao", "CW", NorthAmerica, Caribbean},
+		{"RE", "RE", Africa, EasternAfrica},
+		{"Reunion", "RE", Africa, EasternAfrica},
+		{"GF", "GF", SouthAmerica, SouthAmericaSub},
+		{"French Guiana", "GF", SouthAmerica, SouthAmericaSub},
+		{"MQ", "MQ", NorthAmerica, Caribbean},
+		{"NC", "NC", Oceania, Melanesia},
+		{"New Caledonia", "NC", Oceania, Melanesia},
+		{"the Netherlands", "NL", Europe, WesternEurope},
+		{"Holland", "NL", Europe, WesternEurope},
+		{"  England ", "GB", Europe, NorthernEurope},
+		{"USA", "US", NorthAmerica, NorthernAmerica},
+	}
+	for _, tt := range tests {
+		r, ok := regions.Lookup(tt.in)
+		if !ok {
+			t.Errorf("Lookup(%q) not found", tt.in)
+			continue
+		}
+		if r.Code != tt.code || r.Continent != tt.continent || r.Subregion != tt.subregion {
+			t.Errorf("Lookup(%q) = %s %s %s, want %s %s %s", tt.in, r.Code, r.Continent, r.Subregion, tt.code, tt.continent, tt.subregion)
+		}
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	regions := New()
+	for _, in := range []string{"", "  ", "XX", "Atlantis", "Berlin"} {
+		if r, ok := regions.Lookup(in); ok {
+			t.Errorf("Lookup(%q) = %s, want not found", in, r.Code)
+		}
+	}
+}
+
+func TestSameContinent(t *testing.T) {
+	regions := New()
+	tests := []struct {
+		c1, c2 string
+		want   bool
+	}{
+		{"Germany", "Russia", true},
+		{"Germany", "Turkey", false},
+		{"Turkey", "Japan", true},
+		{"Martinique", "United States", true},
+		{"Martinique", "France", false},
+		{"Reunion", "Kenya", true},
+		{"French Guiana", "Brazil", true},
+		{"New Caledonia", "Australia", true},
+		{"Germany", "Atlantis", false},
+	}
+	for _, tt := range tests {
+		if got := regions.SameContinent(tt.c1, tt.c2); got != tt.want {
+			t.Errorf("SameContinent(%q, %q) = %v, want %v", tt.c1, tt.c2, got, tt.want)
+		}
+	}
+}
+
+func TestSameForeignContinent(t *testing.T) {
+	regions := New()
+	tests := []struct {
+		c1, c2, home string
+		want         bool
+	}{
+		// A European artist touring North America and the Caribbean stays there.
+		{"US", "Puerto Rico", "Germany", true},
+		{"Trinidad and Tobago", "Curaçao", "UK", true},
+		// Gigs on the artist's own continent are never foreign.
+		{"France", "Germany", "Netherlands", false},
+		{"Russia", "Germany", "Netherlands", false},
+		// Turkey is in Asia, so a Berlin artist flying Istanbul to Tokyo stays abroad,
+		// and Istanbul to Moscow crosses continents.
+		{"Turkey", "Japan", "Germany", true},
+		{"Türkiye", "Russian Federation", "Germany", false},
+		{"Turkey", "Russia", "Japan", false},
+		// Overseas territories go with where they are, not with France.
+		{"Martinique", "France", "US", false},
+		{"Reunion", "Kenya", "France", true},
+		{"French Guiana", "Brazil", "France", true},
+		{"New Caledonia", "Australia", "France", true},
+		// Unknown countries fall back to flying home.
+		{"US", "Atlantis", "Germany", false},
+		{"US", "Canada", "", false},
+	}
+	for _, tt := range tests {
+		if got := regions.SameForeignContinent(tt.c1, tt.c2, tt.home); got != tt.want {
+			t.Errorf("SameForeignContinent(%q, %q, home %q) = %v, want %v", tt.c1, tt.c2, tt.home, got, tt.want)
+		}
+	}
+}
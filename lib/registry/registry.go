@@ -0,0 +1,444 @@
+package registry
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Reason an artist was left out of the study, as listed in the README.
+type Reason string
+
+const (
+	Band       Reason = "band"         // tours as a band, with a crew and usually by bus
+	Deceased   Reason = "deceased"     // has passed away
+	NoTour     Reason = "no-tour"      // did not tour at all in the study year
+	NoHometown Reason = "no-hometown"  // hometown not visible on both RA and SoundCloud
+	NoFly      Reason = "does-not-fly" // independently verified not to fly
+)
+
+var Reasons = []Reason{Band, Deceased, NoTour, NoHometown, NoFly}
+
+// Where an artist's hometown was taken from.
+type Source string
+
+const (
+	SoundCloud Source = "soundcloud"
+	RA         Source = "ra"
+	Manual     Source = "manual"
+)
+
+type Entry struct {
+	Name           string `json:"name" yaml:"name"`
+	RASlug         string `json:"ra_slug,omitempty" yaml:"ra_slug,omitempty"`
+	SoundCloudUrl  string `json:"soundcloud_url,omitempty" yaml:"soundcloud_url,omitempty"`
+	City           string `json:"city,omitempty" yaml:"city,omitempty"`
+	Country        string `json:"country,omitempty" yaml:"country,omitempty"`
+	HomeAirport    string `json:"home_airport,omitempty" yaml:"home_airport,omitempty"`
+	Members        int    `json:"members,omitempty" yaml:"members,omitempty"`
+	Crew           int    `json:"crew,omitempty" yaml:"crew,omitempty"`
+	EventsTotal    int    `json:"events_total,omitempty" yaml:"events_total,omitempty"`
+	Excluded       Reason `json:"excluded,omitempty" yaml:"excluded,omitempty"`
+	HometownSource Source `json:"hometown_source,omitempty" yaml:"hometown_source,omitempty"`
+	// Event sources in order of precedence, e.g. "ra" or "ra;file:gigs/x.csv".
+	Sources []string `json:"sources,omitempty" yaml:"sources,omitempty"`
+
+	// Line of the entry in the registry file, for error messages.
+	Line int `json:"-" yaml:"-"`
+}
+
+// Errors collects every problem found in a registry file.
+type Errors []error
+
+func (errs Errors) Error() string {
+	var msgs []string
+	for _, err := range errs {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+/*
+Load reads an artist registry. Files ending in .json hold an array of
+entries, .yaml and .yml files a list of them. Anything else is CSV with a
+header naming the columns, e.g.
+
+	name,ra_slug,city,country,members,excluded
+	"Tiga, Live",tiga,Montreal,Canada,1,
+
+The original headerless name,city,country,event count list is still read.
+*/
+func Load(fname string) ([]Entry, error) {
+	entries, err := read(fname)
+	if err != nil {
+		return entries, err
+	}
+	return entries, validate(fname, entries, true)
+}
+
+// LoadUnresolved reads a registry whose hometowns are still to be found.
+func LoadUnresolved(fname string) ([]Entry, error) {
+	entries, err := read(fname)
+	if err != nil {
+		return entries, err
+	}
+	return entries, validate(fname, entries, false)
+}
+
+func read(fname string) ([]Entry, error) {
+	switch {
+	case isJSON(fname):
+		return loadJSON(fname)
+	case isYAML(fname):
+		return loadYAML(fname)
+	}
+	return loadCSV(fname)
+}
+
+func isJSON(fname string) bool {
+	return strings.EqualFold(filepath.Ext(fname), ".json")
+}
+
+func isYAML(fname string) bool {
+	ext := strings.ToLower(filepath.Ext(fname))
+	return ext == ".yaml" || ext == ".yml"
+}
+
+/*
+A YAML registry is a list of entries with the same keys as the JSON one:
+
+  - name: Tiga
+    ra_slug: tiga
+    city: Montreal
+    country: Canada
+    sources: [ra, "file:gigs/tiga.csv"]
+*/
+func loadYAML(fname string) ([]Entry, error) {
+	var entries []Entry
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return entries, err
+	}
+	if err := yaml.UnmarshalStrict(data, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	// yaml.v2 keeps no positions, entries start at the unindented dashes.
+	lines := itemLines(data)
+	for i := range entries {
+		if i < len(lines) {
+			entries[i].Line = lines[i]
+		}
+	}
+	return entries, nil
+}
+
+func itemLines(data []byte) []int {
+	var lines []int
+	for i, line := range strings.Split(string(data), "\n") {
+		if strings.HasPrefix(line, "-") && !strings.HasPrefix(line, "---") {
+			lines = append(lines, i+1)
+		}
+	}
+	return lines
+}
+
+func loadJSON(fname string) ([]Entry, error) {
+	var entries []Entry
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return entries, err
+	}
+	dec := json.NewDecoder(strings.NewReader(string(data)))
+	if _, err := dec.Token(); err != nil {
+		return entries, fmt.Errorf("%s:1: expected an array of artists: %v", fname, err)
+	}
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+		var entry Entry
+		if err := dec.Decode(&entry); err != nil {
+			return entries, fmt.Errorf("%s:%d: %v", fname, line, err)
+		}
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// The offset sits just before the next entry, skip to where it starts.
+func lineAt(data []byte, offset int64) int {
+	for offset < int64(len(data)) && strings.ContainsRune(", \t\r\n", rune(data[offset])) {
+		offset++
+	}
+	return strings.Count(string(data[:offset]), "\n") + 1
+}
+
+var legacyColumns = []string{"name", "city", "country", "events_total", "members", "crew"}
+
+func loadCSV(fname string) ([]Entry, error) {
+	var entries []Entry
+	file, err := os.Open(fname)
+	if err != nil {
+		return entries, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	var (
+		columns []string
+		line    int
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return entries, fmt.Errorf("%s:%d: %v", fname, line, err)
+		}
+		if columns == nil {
+			columns = header(record)
+			if columns != nil {
+				continue
+			}
+			columns = legacyColumns
+		}
+		entry, err := parseRecord(columns, record)
+		if err != nil {
+			return entries, fmt.Errorf("%s:%d: %v", fname, line, err)
+		}
+		entry.Line = line
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// Returns the column names if the record is a header, nil for legacy files.
+func header(record []string) []string {
+	var columns []string
+	isHeader := false
+	for _, col := range record {
+		col = strings.ToLower(strings.TrimSpace(col))
+		if col == "name" {
+			isHeader = true
+		}
+		columns = append(columns, col)
+	}
+	if !isHeader {
+		return nil
+	}
+	return columns
+}
+
+func parseRecord(columns, record []string) (Entry, error) {
+	var entry Entry
+	for i, value := range record {
+		if i >= len(columns) {
+			return entry, fmt.Errorf("%d fields but only %d columns", len(record), len(columns))
+		}
+		value = strings.TrimSpace(value)
+		var err error
+		switch columns[i] {
+		case "name":
+			entry.Name = value
+		case "ra_slug":
+			entry.RASlug = value
+		case "soundcloud_url":
+			entry.SoundCloudUrl = value
+		case "city":
+			entry.City = value
+		case "country":
+			entry.Country = value
+		case "home_airport":
+			entry.HomeAirport = value
+		case "members":
+			entry.Members, err = atoi(value)
+		case "crew":
+			entry.Crew, err = atoi(value)
+		case "events_total":
+			entry.EventsTotal, err = atoi(value)
+		case "excluded":
+			entry.Excluded = Reason(strings.ToLower(value))
+		case "hometown_source":
+			entry.HometownSource = Source(strings.ToLower(value))
+		case "sources":
+			for _, spec := range strings.Split(value, ";") {
+				if spec = strings.TrimSpace(spec); spec != "" {
+					entry.Sources = append(entry.Sources, spec)
+				}
+			}
+		default:
+			return entry, fmt.Errorf("unknown column %q", columns[i])
+		}
+		if err != nil {
+			return entry, fmt.Errorf("%s: %v", columns[i], err)
+		}
+	}
+	return entry, nil
+}
+
+func atoi(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(value)
+}
+
+func validate(fname string, entries []Entry, needHometown bool) error {
+	var (
+		errs Errors
+		seen = make(map[string]int)
+	)
+	for i := range entries {
+		entry := &entries[i]
+		fail := func(msg string, args ...interface{}) {
+			errs = append(errs, fmt.Errorf("%s:%d: %s", fname, entry.Line, fmt.Sprintf(msg, args...)))
+		}
+		if entry.Name == "" {
+			fail("missing artist name")
+			continue
+		}
+		if line, ok := seen[entry.Name]; ok {
+			fail("%q already listed on line %d", entry.Name, line)
+		}
+		seen[entry.Name] = entry.Line
+		if entry.Members == 0 {
+			entry.Members = 1
+		}
+		entry.HomeAirport = strings.ToUpper(entry.HomeAirport)
+		if entry.Members < 0 || entry.Crew < 0 || entry.EventsTotal < 0 {
+			fail("negative member, crew or event count for %q", entry.Name)
+		}
+		if entry.Excluded != "" && !knownReason(entry.Excluded) {
+			fail("unknown exclusion reason %q, expected one of %v", entry.Excluded, Reasons)
+		}
+		switch entry.HometownSource {
+		case "", SoundCloud, RA, Manual:
+		default:
+			fail("unknown hometown source %q", entry.HometownSource)
+		}
+		if entry.HomeAirport != "" && !isIata(entry.HomeAirport) {
+			fail("home airport %q is not an IATA code", entry.HomeAirport)
+		}
+		if strings.Contains(entry.RASlug, "/") {
+			fail("ra slug %q should not be a path", entry.RASlug)
+		}
+		if entry.SoundCloudUrl != "" {
+			if u, err := url.Parse(entry.SoundCloudUrl); err != nil || !strings.HasSuffix(u.Host, "soundcloud.com") {
+				fail("%q is not a soundcloud url", entry.SoundCloudUrl)
+			}
+		}
+		for _, spec := range entry.Sources {
+			if strings.HasSuffix(spec, ":") || strings.HasPrefix(spec, ":") {
+				fail("event source %q is missing its name or location", spec)
+			}
+		}
+		if needHometown && entry.Excluded == "" && entry.HomeAirport == "" && (entry.City == "" || entry.Country == "") {
+			fail("%q needs a city and country or a home airport", entry.Name)
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+var columns = []string{"name", "ra_slug", "soundcloud_url", "city", "country", "home_airport", "members", "crew", "events_total", "excluded", "hometown_source", "sources"}
+
+/*
+Save writes the registry back in the format its name calls for, CSV files
+always with a header. It is written to a temporary file next to fname and
+moved over it once complete, so a failed save leaves the old registry as it
+was.
+*/
+func Save(fname string, entries []Entry) error {
+	var perm os.FileMode = 0644
+	if info, err := os.Stat(fname); err == nil {
+		perm = info.Mode().Perm()
+	}
+	file, err := ioutil.TempFile(filepath.Dir(fname), "."+filepath.Base(fname)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+	err = encode(file, fname, entries)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(file.Name(), perm)
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), fname)
+}
+
+func encode(w io.Writer, fname string, entries []Entry) error {
+	if isJSON(fname) {
+		data, err := json.MarshalIndent(entries, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(append(data, '\n'))
+		return err
+	}
+	if isYAML(fname) {
+		data, err := yaml.Marshal(entries)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+	writer := csv.NewWriter(w)
+	writer.Write(columns)
+	for _, e := range entries {
+		writer.Write([]string{
+			e.Name, e.RASlug, e.SoundCloudUrl, e.City, e.Country, e.HomeAirport,
+			itoa(e.Members), itoa(e.Crew), itoa(e.EventsTotal),
+			string(e.Excluded), string(e.HometownSource), strings.Join(e.Sources, ";"),
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func itoa(n int) string {
+	if n == 0 {
+		return ""
+	}
+	return strconv.Itoa(n)
+}
+
+func knownReason(r Reason) bool {
+	for _, reason := range Reasons {
+		if r == reason {
+			return true
+		}
+	}
+	return false
+}
+
+func isIata(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, c := range code {
+		if c < 'A' || c > 'Z' {
+			return false
+		}
+	}
+	return true
+}
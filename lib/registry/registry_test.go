@@ -0,0 +1,134 @@
+package registry
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, data string) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "registry")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	fName := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(fName, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return fName
+}
+
+var tiga = Entry{
+	Name: "Tiga", RASlug: "tiga", City: "Montreal", Country: "Canada",
+	Members: 1, Crew: 1, Sources: []string{"ra", "file:gigs/tiga.csv"},
+}
+
+func TestLoadFormats(t *testing.T) {
+	tests := []struct {
+		name, data string
+	}{
+		{"artists.csv", "name,ra_slug,city,country,crew,sources\nTiga,tiga,Montreal,Canada,1,ra;file:gigs/tiga.csv\n"},
+		{"artists.json", `[{"name": "Tiga", "ra_slug": "tiga", "city": "Montreal", "country": "Canada", "crew": 1, "sources": ["ra", "file:gigs/tiga.csv"]}]`},
+		{"artists.yaml", "- name: Tiga\n  ra_slug: tiga\n  city: Montreal\n  country: Canada\n  crew: 1\n  sources: [ra, \"file:gigs/tiga.csv\"]\n"},
+		{"artists.yml", "---\n- name: Tiga\n  ra_slug: tiga\n  city: Montreal\n  country: Canada\n  crew: 1\n  sources:\n    - ra\n    - file:gigs/tiga.csv\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := Load(writeFile(t, tt.name, tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			got := entries[0]
+			got.Line = 0
+			if !reflect.DeepEqual(got, tiga) {
+				t.Errorf("got %+v, want %+v", got, tiga)
+			}
+		})
+	}
+}
+
+func TestLoadLegacyCSV(t *testing.T) {
+	entries, err := Load(writeFile(t, "artists.csv", "Tiga,Montreal,Canada,42\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "Tiga" || entries[0].EventsTotal != 42 || entries[0].Members != 1 {
+		t.Errorf("got %+v", entries)
+	}
+}
+
+func TestLoadYAMLErrors(t *testing.T) {
+	data := "- name: Tiga\n  city: Montreal\n  country: Canada\n- name: Tiga\n  excluded: retired\n"
+	_, err := Load(writeFile(t, "artists.yaml", data))
+	if err == nil {
+		t.Fatal("want validation errors")
+	}
+	for _, want := range []string{`artists.yaml:4: "Tiga" already listed on line 1`, `artists.yaml:4: unknown exclusion reason "retired"`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("got %v, want %q", err, want)
+		}
+	}
+	if _, err := Load(writeFile(t, "artists.yaml", "- name: Tiga\n  hometown: Montreal\n")); err == nil {
+		t.Error("unknown keys are an error")
+	}
+}
+
+func TestSaveRoundTrip(t *testing.T) {
+	for _, name := range []string{"artists.csv", "artists.json", "artists.yaml"} {
+		t.Run(name, func(t *testing.T) {
+			fName := writeFile(t, name, "")
+			if err := Save(fName, []Entry{tiga}); err != nil {
+				t.Fatal(err)
+			}
+			entries, err := Load(fName)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(entries) != 1 {
+				t.Fatalf("got %d entries, want 1", len(entries))
+			}
+			got := entries[0]
+			got.Line = 0
+			if !reflect.DeepEqual(got, tiga) {
+				t.Errorf("got %+v, want %+v", got, tiga)
+			}
+		})
+	}
+}
+
+// Saving over the registry replaces it whole, keeping its permissions and
+// leaving nothing else behind.
+func TestSaveReplaces(t *testing.T) {
+	fName := writeFile(t, "artists.csv", "name,city\nTiga,\n")
+	if err := os.Chmod(fName, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(fName, []Entry{tiga}); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("saved with mode %v, want 0600", info.Mode().Perm())
+	}
+	files, err := ioutil.ReadDir(filepath.Dir(fName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Errorf("got %d files after saving, want only the registry", len(files))
+	}
+	if err := Save(filepath.Join(filepath.Dir(fName), "missing", "artists.csv"), nil); err == nil {
+		t.Error("saved into a missing directory")
+	}
+}
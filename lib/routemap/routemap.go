@@ -0,0 +1,207 @@
+package routemap
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/cleanscene.flights/lib/airports"
+	"github.com/cleanscene.flights/lib/geo"
+	"github.com/cleanscene.flights/lib/output"
+)
+
+// Route is a flight drawn as its great-circle arc, in more than one part
+// when it crosses the antimeridian.
+type Route struct {
+	Flight output.Flight
+	Path   [][]geo.Point
+}
+
+/*
+Build draws each flight from its departure to its arrival airport, with the
+arc interpolated every 100 km or so, at least points times. Flights with
+an airport missing from coords are left out, their codes returned sorted.
+*/
+func Build(flights []output.Flight, coords airports.Coords, points int) ([]Route, []string) {
+	var (
+		routes  []Route
+		missing = make(map[string]bool)
+	)
+	for _, f := range flights {
+		from, ok1 := coords[f.Departure]
+		to, ok2 := coords[f.Arrival]
+		if !ok1 {
+			missing[f.Departure] = true
+		}
+		if !ok2 {
+			missing[f.Arrival] = true
+		}
+		if !ok1 || !ok2 || f.Departure == f.Arrival {
+			continue
+		}
+		n := int(geo.Distance(from.Lat, from.Lng, to.Lat, to.Lng) / 100)
+		if n < points {
+			n = points
+		}
+		routes = append(routes, Route{Flight: f, Path: geo.SplitAtAntimeridian(geo.Arc(from, to, n))})
+	}
+	var codes []string
+	for code := range missing {
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return routes, codes
+}
+
+type property struct {
+	name  string
+	value interface{}
+}
+
+// Properties of every route, in GeoJSON and KML alike.
+func properties(f output.Flight) []property {
+	return []property{
+		{"artist", f.Artist},
+		{"artist_id", f.ArtistID},
+		{"date", f.Date},
+		{"departure", f.Departure},
+		{"arrival", f.Arrival},
+		{"via", f.Via},
+		{"leg_type", string(f.Leg)},
+		{"tour", f.Tour},
+		{"passengers", f.Passengers},
+		{"carbon_kg", f.CarbonKg},
+		{"carbon_per_person_kg", f.CarbonPerPersonKg},
+		{"distance_km", f.DistanceKm},
+		{"methodology", f.Methodology},
+	}
+}
+
+// GeoJSON positions are [lng, lat], rounded to about a metre.
+func position(p geo.Point) [2]float64 {
+	round := func(v float64) float64 { return math.Round(v*1e5) / 1e5 }
+	return [2]float64{round(p.Lng), round(p.Lat)}
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Geometry   geometry               `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// WriteGeoJSON writes the routes as a FeatureCollection of LineStrings,
+// MultiLineStrings for those split at the antimeridian.
+func WriteGeoJSON(w io.Writer, routes []Route) error {
+	collection := struct {
+		Type     string    `json:"type"`
+		Features []feature `json:"features"`
+	}{Type: "FeatureCollection", Features: []feature{}}
+	for _, r := range routes {
+		var lines [][][2]float64
+		for _, part := range r.Path {
+			var line [][2]float64
+			for _, p := range part {
+				line = append(line, position(p))
+			}
+			lines = append(lines, line)
+		}
+		geom := geometry{Type: "MultiLineString", Coordinates: lines}
+		if len(lines) == 1 {
+			geom = geometry{Type: "LineString", Coordinates: lines[0]}
+		}
+		props := make(map[string]interface{})
+		for _, p := range properties(r.Flight) {
+			props[p.name] = p.value
+		}
+		collection.Features = append(collection.Features, feature{Type: "Feature", Geometry: geom, Properties: props})
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", " ")
+	return enc.Encode(collection)
+}
+
+type kml struct {
+	XMLName  xml.Name    `xml:"http://www.opengis.net/kml/2.2 kml"`
+	Document kmlDocument `xml:"Document"`
+}
+
+type kmlDocument struct {
+	Name       string         `xml:"name"`
+	Style      kmlStyle       `xml:"Style"`
+	Placemarks []kmlPlacemark `xml:"Placemark"`
+}
+
+type kmlStyle struct {
+	ID    string `xml:"id,attr"`
+	Color string `xml:"LineStyle>color"`
+	Width int    `xml:"LineStyle>width"`
+}
+
+type kmlPlacemark struct {
+	Name        string     `xml:"name"`
+	Description string     `xml:"description"`
+	StyleURL    string     `xml:"styleUrl"`
+	Data        []kmlData  `xml:"ExtendedData>Data"`
+	Lines       []kmlLines `xml:"MultiGeometry>LineString"`
+}
+
+type kmlData struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+}
+
+type kmlLines struct {
+	Tessellate  int    `xml:"tessellate"`
+	Coordinates string `xml:"coordinates"`
+}
+
+// WriteKML writes the routes as a KML document for Google Earth, each a
+// placemark with the flight's figures as its extended data.
+func WriteKML(w io.Writer, name string, routes []Route) error {
+	doc := kml{Document: kmlDocument{
+		Name: name,
+		// KML colours are aabbggrr, a semi-transparent red
+		Style: kmlStyle{ID: "flight", Color: "b31c1ce3", Width: 2},
+	}}
+	for _, r := range routes {
+		f := r.Flight
+		pm := kmlPlacemark{
+			Name:        fmt.Sprintf("%s %s-%s", f.Date, f.Departure, f.Arrival),
+			Description: fmt.Sprintf("%s, %.0f kg CO2, %d km", f.Artist, f.CarbonKg, f.DistanceKm),
+			StyleURL:    "#flight",
+		}
+		for _, p := range properties(f) {
+			pm.Data = append(pm.Data, kmlData{Name: p.name, Value: fmt.Sprint(p.value)})
+		}
+		for _, part := range r.Path {
+			var coords []string
+			for _, p := range part {
+				pos := position(p)
+				coords = append(coords, fmt.Sprintf("%g,%g,0", pos[0], pos[1]))
+			}
+			pm.Lines = append(pm.Lines, kmlLines{Tessellate: 1, Coordinates: strings.Join(coords, " ")})
+		}
+		doc.Document.Placemarks = append(doc.Document.Placemarks, pm)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", " ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
@@ -0,0 +1,299 @@
+package source
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/geo"
+	"github.com/cleanscene.flights/lib/region"
+)
+
+// Listing is one source's geocoded events for an artist.
+type Listing struct {
+	Source string
+	Events crawler.Events
+}
+
+const (
+	// Gigs this close together are at the same venue.
+	sameVenueKm = 5.0
+	// Title or venue similarity needed when the gigs fly into the same
+	// airport, and when nothing else matches.
+	similarMatch = 0.6
+	strongMatch  = 0.85
+
+	// Words two names share before the shorter one may match on them alone.
+	minSharedWords = 2
+	// Gigs further apart than this are never the same, whatever they are called.
+	differentPlaceKm = 150.0
+
+	// How fast an artist could possibly get from one gig to the next.
+	cruiseKmh     = 800.0
+	transferHours = 3.0
+)
+
+type dated struct {
+	date  time.Time
+	event event.Event
+	// Every source merged into the event, each lists a gig once
+	sources map[string]bool
+}
+
+/*
+Dedupe merges an artist's listings, given in order of precedence.
+
+The same gig listed by two sources is recognised when the dates are at most
+a day apart (sets after midnight get listed on either day) and the venues
+are within sameVenueKm, or they fly into the same airport with similar
+titles or venues, or the titles or venues are near identical. Gigs more
+than differentPlaceKm apart are never the same. The merged
+gig takes its fields from the source with precedence, filling gaps from the
+others.
+
+Gigs left too far apart to travel between in time are flagged for review.
+*/
+func Dedupe(listings []Listing) crawler.Events {
+	var merged []dated
+	for _, listing := range listings {
+		for _, d := range listing.Events.Dates() {
+			for _, e := range listing.Events[d] {
+				if e.Source == "" {
+					e.Source = listing.Source
+				}
+				if i := findDuplicate(merged, d, e); i >= 0 {
+					merged[i].event = mergeFields(merged[i].event, e)
+					merged[i].sources[e.Source] = true
+					continue
+				}
+				merged = append(merged, dated{date: d, event: e, sources: map[string]bool{e.Source: true}})
+			}
+		}
+	}
+	sortGigs(merged)
+	flagImpossible(merged)
+
+	var events = make(crawler.Events)
+	for _, m := range merged {
+		events.Add(m.date, m.event)
+	}
+	return events
+}
+
+// By date, gigs on the same day by their set times when both have them and
+// as listed otherwise.
+func sortGigs(gigs []dated) {
+	sort.SliceStable(gigs, func(i, j int) bool {
+		if !gigs[i].date.Equal(gigs[j].date) {
+			return gigs[i].date.Before(gigs[j].date)
+		}
+		s1, s2 := gigs[i].event.Start, gigs[j].event.Start
+		return !s1.IsZero() && !s2.IsZero() && s1.Before(s2)
+	})
+}
+
+func findDuplicate(merged []dated, d time.Time, e event.Event) int {
+	for i, m := range merged {
+		if m.sources[e.Source] || dayDiff(m.date, d) > 1 {
+			continue
+		}
+		if isDuplicate(m.event, e) {
+			return i
+		}
+	}
+	return -1
+}
+
+func dayDiff(d1, d2 time.Time) int {
+	y1, m1, dd1 := d1.Date()
+	y2, m2, dd2 := d2.Date()
+	days := time.Date(y2, m2, dd2, 0, 0, 0, 0, time.UTC).Sub(time.Date(y1, m1, dd1, 0, 0, 0, 0, time.UTC)).Hours() / 24
+	if days < 0 {
+		days = -days
+	}
+	return int(days)
+}
+
+func isDuplicate(e1, e2 event.Event) bool {
+	similar := max(similarity(e1.Title, e2.Title), similarity(e1.Location, e2.Location))
+	if hasCoords(e1) && hasCoords(e2) {
+		km := geo.Distance(e1.Lat, e1.Lng, e2.Lat, e2.Lng)
+		if km <= sameVenueKm {
+			return true
+		}
+		if km > differentPlaceKm {
+			return false
+		}
+	}
+	if e1.AirCode != "" && e1.AirCode == e2.AirCode && similar >= similarMatch {
+		return true
+	}
+	return similar >= strongMatch
+}
+
+func hasCoords(e event.Event) bool { return e.Lat != 0 || e.Lng != 0 }
+
+// Keeps the fields of the event with precedence, filling in what it lacks.
+func mergeFields(primary, other event.Event) event.Event {
+	fill := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	fill(&primary.Title, other.Title)
+	fill(&primary.Location, other.Location)
+	fill(&primary.Venue, other.Venue)
+	fill(&primary.VenueID, other.VenueID)
+	fill(&primary.City, other.City)
+	fill(&primary.Country, other.Country)
+	fill(&primary.CountryCode, other.CountryCode)
+	fill(&primary.AirCode, other.AirCode)
+	fill(&primary.TimeZone, other.TimeZone)
+	if !hasCoords(primary) {
+		primary.Lat, primary.Lng = other.Lat, other.Lng
+	}
+	if primary.Start.IsZero() {
+		primary.Start, primary.End = other.Start, other.End
+	}
+	primary.Flags = append(primary.Flags, other.Flags...)
+	// Placed by the other source
+	if primary.AirCode != "" {
+		primary.Failure = ""
+	}
+	return primary
+}
+
+func flagImpossible(merged []dated) {
+	for i := 0; i+1 < len(merged); i++ {
+		e1, e2 := merged[i].event, merged[i+1].event
+		if !hasCoords(e1) || !hasCoords(e2) {
+			continue
+		}
+		km := geo.Distance(e1.Lat, e1.Lng, e2.Lat, e2.Lng)
+		gap := hoursBetween(merged[i], merged[i+1])
+		needed := km/cruiseKmh + transferHours
+		if km <= sameVenueKm || gap >= needed {
+			continue
+		}
+		msg := fmt.Sprintf("impossible schedule: %s and %s are %.0f km apart but only %.0fh between them", name(e1), name(e2), km, gap)
+		merged[i].event.Flags = append(merged[i].event.Flags, msg)
+		merged[i+1].event.Flags = append(merged[i+1].event.Flags, msg)
+	}
+}
+
+// Time between the end of one gig and the start of the next. Without set
+// times, gigs on the same day are taken to be a working day apart.
+func hoursBetween(g1, g2 dated) float64 {
+	if !g1.event.Start.IsZero() && !g2.event.Start.IsZero() {
+		end := g1.event.End
+		if end.IsZero() {
+			end = g1.event.Start
+		}
+		return g2.event.Start.Sub(end).Hours()
+	}
+	days := dayDiff(g1.date, g2.date)
+	if days == 0 {
+		return 8
+	}
+	return float64(days * 24)
+}
+
+func name(e event.Event) string {
+	if e.Title != "" {
+		return e.Title
+	}
+	return e.Location
+}
+
+// Similarity of two names between 0 and 1, the better of word overlap and
+// edit distance, ignoring case, accents and punctuation.
+func similarity(a, b string) float64 {
+	a, b = region.Fold(a), region.Fold(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	return max(wordOverlap(a, b), 1-float64(levenshtein(a, b))/float64(maxLen(a, b)))
+}
+
+/*
+Share of the shorter name's words found in the other, so "Sonar Night" and
+"Sonar By Night" match, once they share minSharedWords. With fewer, the
+share of all their words, so a one word title like "Closing" does not match
+every closing party.
+*/
+func wordOverlap(a, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) > len(wb) {
+		wa, wb = wb, wa
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	if shared >= minSharedWords {
+		return float64(shared) / float64(len(wa))
+	}
+	return float64(shared) / float64(len(wa)+len(wb)-shared)
+}
+
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(s) {
+		set[w] = true
+	}
+	return set
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func maxLen(a, b string) int {
+	la, lb := len([]rune(a)), len([]rune(b))
+	if la > lb {
+		return la
+	}
+	return lb
+}
+
+func max(a, b float64) float64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
@@ -0,0 +1,169 @@
+package source
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/event"
+)
+
+func date(s string) time.Time {
+	d, _ := time.Parse("2006-01-02", s)
+	return d
+}
+
+func listing(src string, gigs map[string][]event.Event) Listing {
+	events := make(crawler.Events)
+	for d, day := range gigs {
+		for _, e := range day {
+			events.Add(date(d), e)
+		}
+	}
+	return Listing{Source: src, Events: events}
+}
+
+var (
+	berghain   = event.Event{Title: "Klubnacht", Venue: "Berghain", Location: "Am Wriezener Bahnhof, Berlin", Lat: 52.511, Lng: 13.443, AirCode: "TXL"}
+	tresor     = event.Event{Title: "Tresor Nights", Venue: "Tresor", Location: "Köpenicker Str. 70, Berlin", Lat: 52.510, Lng: 13.419, AirCode: "TXL"}
+	sonarBCN   = event.Event{Title: "Sonar", Location: "Fira Gran Via, Barcelona", Lat: 41.354, Lng: 2.128, AirCode: "BCN"}
+	sonarTokyo = event.Event{Title: "Sonar", Location: "Shibuya, Tokyo", Lat: 35.658, Lng: 139.701, AirCode: "HND"}
+)
+
+func titles(events crawler.Events) []string {
+	var got []string
+	for _, d := range events.Dates() {
+		for _, e := range events[d] {
+			got = append(got, d.Format("2006-01-02")+" "+e.Title+" ("+e.Source+")")
+		}
+	}
+	return got
+}
+
+func TestDedupe(t *testing.T) {
+	tests := []struct {
+		name     string
+		listings []Listing
+		want     []string
+	}{
+		{
+			name: "same venue, different title",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-03-01": {berghain}}),
+				listing("file", map[string][]event.Event{"2019-03-01": {{Title: "Ostgut Ton Night", Lat: 52.512, Lng: 13.441}}}),
+			},
+			want: []string{"2019-03-01 Klubnacht (ra)"},
+		},
+		{
+			name: "different venue, same title",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-06-15": {sonarBCN}}),
+				listing("ical", map[string][]event.Event{"2019-06-15": {sonarTokyo}}),
+			},
+			want: []string{"2019-06-15 Sonar (ra)", "2019-06-15 Sonar (ical)"},
+		},
+		{
+			name: "across midnight",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-03-01": {berghain}}),
+				listing("file", map[string][]event.Event{"2019-03-02": {{Title: "Klubnacht", Location: "Berghain, Berlin"}}}),
+			},
+			want: []string{"2019-03-01 Klubnacht (ra)"},
+		},
+		{
+			name: "a week apart",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-03-01": {berghain}}),
+				listing("file", map[string][]event.Event{"2019-03-08": {berghain}}),
+			},
+			want: []string{"2019-03-01 Klubnacht (ra)", "2019-03-08 Klubnacht (file)"},
+		},
+		{
+			name: "one word title",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-09-28": {{Title: "Closing", Location: "Ibiza"}}}),
+				listing("file", map[string][]event.Event{"2019-09-28": {{Title: "Closing Party at Fabric", Location: "London"}}}),
+			},
+			want: []string{"2019-09-28 Closing (ra)", "2019-09-28 Closing Party at Fabric (file)"},
+		},
+		{
+			name: "titles sharing two words",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-06-14": {{Title: "Sonar By Night"}}}),
+				listing("file", map[string][]event.Event{"2019-06-14": {{Title: "Sonar Night"}}}),
+			},
+			want: []string{"2019-06-14 Sonar By Night (ra)"},
+		},
+		{
+			name: "two gigs at one venue from a source merged in",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-03-01": {berghain}}),
+				listing("file", map[string][]event.Event{"2019-03-01": {
+					{Title: "Klubnacht", Lat: 52.511, Lng: 13.443},
+					{Title: "Panorama Bar Sunday", Lat: 52.511, Lng: 13.443},
+				}}),
+			},
+			want: []string{"2019-03-01 Klubnacht (ra)", "2019-03-01 Panorama Bar Sunday (file)"},
+		},
+		{
+			name: "two gigs on one day from one source",
+			listings: []Listing{
+				listing("ra", map[string][]event.Event{"2019-03-01": {berghain, tresor}}),
+				listing("file", map[string][]event.Event{"2019-03-01": {{Title: "Tresor Nights", Location: "Tresor, Berlin"}}}),
+			},
+			want: []string{"2019-03-01 Klubnacht (ra)", "2019-03-01 Tresor Nights (ra)"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := titles(Dedupe(tt.listings))
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupeFillsGaps(t *testing.T) {
+	events := Dedupe([]Listing{
+		listing("ra", map[string][]event.Event{"2019-03-01": {{Title: "Klubnacht", Venue: "Berghain", Location: "Berghain, Berlin"}}}),
+		listing("file", map[string][]event.Event{"2019-03-01": {berghain}}),
+	})
+	day := events[date("2019-03-01")]
+	if len(day) != 1 {
+		t.Fatalf("got %d gigs, want them merged", len(day))
+	}
+	if e := day[0]; e.Source != "ra" || e.Location != "Berghain, Berlin" || e.AirCode != "TXL" || e.Lat == 0 {
+		t.Errorf("want ra's fields with the file's airport and coordinates, got %+v", e)
+	}
+}
+
+func TestDedupeFlagsImpossible(t *testing.T) {
+	events := Dedupe([]Listing{
+		listing("ra", map[string][]event.Event{"2019-06-15": {sonarBCN}}),
+		listing("ical", map[string][]event.Event{"2019-06-15": {sonarTokyo}}),
+	})
+	for _, e := range events[date("2019-06-15")] {
+		if len(e.Flags) != 1 || !strings.HasPrefix(e.Flags[0], "impossible schedule") {
+			t.Errorf("%s flags %q, want an impossible schedule", e.Location, e.Flags)
+		}
+	}
+}
+
+func TestWordOverlap(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"sonar by night", "sonar night", 1},
+		{"boiler", "boiler room berlin", 1.0 / 3},
+		{"closing", "closing party", 0.5},
+		{"klubnacht", "tresor nights", 0},
+	}
+	for _, tt := range tests {
+		if got := wordOverlap(tt.a, tt.b); got != tt.want {
+			t.Errorf("wordOverlap(%q, %q) = %.2f, want %.2f", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/gigfile"
+	"github.com/cleanscene.flights/lib/ical"
+)
+
+// An EventSource lists an artist's gigs, RA being one of several.
+type EventSource interface {
+	// FindArtist returns the source's reference for an artist, a url or file.
+	FindArtist(string) (string, error)
+	// Events lists the gigs between two dates, zero times leave it open.
+	Events(context.Context, string, time.Time, time.Time) (crawler.Events, error)
+}
+
+// Name of the source built from the crawler the service was created with.
+const Default = "ra"
+
+// Set holds the named sources an artist's registry entry can pick from.
+type Set map[string]EventSource
+
+/*
+Resolve turns a registry source spec into an EventSource:
+
+	ra                        a named source in the set
+	file:gigs/artist.csv      a csv or json gig list
+	ical:https://x/gigs.ics   an iCalendar file or feed
+*/
+func (s Set) Resolve(spec string) (EventSource, string, error) {
+	kind := spec
+	ref := ""
+	if i := strings.Index(spec, ":"); i > 0 {
+		kind, ref = spec[:i], spec[i+1:]
+	}
+	switch kind {
+	case "file":
+		return FromCrawler(gigfile.New(ref)), ref, nil
+	case "ical":
+		return FromCrawler(ical.New(ref)), ref, nil
+	}
+	src, ok := s[kind]
+	if !ok && kind == Default {
+		return nil, "", fmt.Errorf("source %s not configured", Default)
+	}
+	if !ok {
+		return nil, "", fmt.Errorf("unknown event source %q", spec)
+	}
+	return src, ref, nil
+}
+
+// FromCrawler adapts a crawler, which works a tour year at a time.
+func FromCrawler(c crawler.Crawler) EventSource {
+	return crawlerSource{c}
+}
+
+type crawlerSource struct {
+	crawler crawler.Crawler
+}
+
+func (s crawlerSource) FindArtist(name string) (string, error) {
+	return s.crawler.GetArtistUrl(name)
+}
+
+func (s crawlerSource) Events(ctx context.Context, ref string, from, to time.Time) (crawler.Events, error) {
+	if from.IsZero() || to.IsZero() {
+		events, err := s.crawler.GetArtistEvents(ctx, ref, "")
+		return Between(events, from, to), err
+	}
+	var events = make(crawler.Events)
+	for year := from.Year(); year <= to.Year(); year++ {
+		yearEvents, err := s.crawler.GetArtistEvents(ctx, ref, strconv.Itoa(year))
+		if err != nil {
+			return events, err
+		}
+		for d, day := range Between(yearEvents, from, to) {
+			events[d] = day
+		}
+	}
+	return events, nil
+}
+
+func Between(events crawler.Events, from, to time.Time) crawler.Events {
+	var inRange = make(crawler.Events)
+	for d, day := range events {
+		if !from.IsZero() && d.Before(from) {
+			continue
+		}
+		if !to.IsZero() && d.After(to) {
+			continue
+		}
+		inRange[d] = day
+	}
+	return inRange
+}
+
+// NewDir is a source of per-artist gig lists in a directory, named after
+// the artist: artist.csv, artist.json or artist.ics.
+func NewDir(dir string) EventSource {
+	return dirSource{dir: dir}
+}
+
+type dirSource struct {
+	dir string
+}
+
+var errNoFile = errors.New("no gig list for artist")
+
+func (s dirSource) FindArtist(name string) (string, error) {
+	for _, ext := range []string{".csv", ".json", ".ics"} {
+		fname := filepath.Join(s.dir, name+ext)
+		if _, err := os.Stat(fname); err == nil {
+			return fname, nil
+		}
+	}
+	return "", errNoFile
+}
+
+func (s dirSource) Events(ctx context.Context, fname string, from, to time.Time) (crawler.Events, error) {
+	if strings.HasSuffix(fname, ".ics") {
+		return FromCrawler(ical.New(fname)).Events(ctx, fname, from, to)
+	}
+	return FromCrawler(gigfile.New(fname)).Events(ctx, fname, from, to)
+}
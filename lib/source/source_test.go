@@ -0,0 +1,42 @@
+package source
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cleanscene.flights/lib/crawler"
+)
+
+type fakeSource struct{}
+
+func (fakeSource) FindArtist(name string) (string, error) { return name, nil }
+
+func (fakeSource) Events(context.Context, string, time.Time, time.Time) (crawler.Events, error) {
+	return make(crawler.Events), nil
+}
+
+func TestResolve(t *testing.T) {
+	sources := Set{"files": fakeSource{}}
+	tests := []struct {
+		spec, ref, err string
+	}{
+		{"files", "", ""},
+		{"file:gigs/tiga.csv", "gigs/tiga.csv", ""},
+		{"ical:https://example.com/gigs.ics", "https://example.com/gigs.ics", ""},
+		{"ra", "", "source ra not configured"},
+		{"bandcamp", "", `unknown event source "bandcamp"`},
+	}
+	for _, tt := range tests {
+		src, ref, err := sources.Resolve(tt.spec)
+		if tt.err != "" {
+			if err == nil || err.Error() != tt.err {
+				t.Errorf("Resolve(%q) error %v, want %s", tt.spec, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || src == nil || ref != tt.ref {
+			t.Errorf("Resolve(%q) = %v, %q, %v, want ref %q", tt.spec, src, ref, err, tt.ref)
+		}
+	}
+}
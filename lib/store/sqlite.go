@@ -0,0 +1,342 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cleanscene.flights/lib/atmos"
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/flight"
+	"github.com/cleanscene.flights/lib/output"
+	"github.com/cleanscene.flights/lib/ra"
+	"github.com/cleanscene.flights/lib/venue"
+
+	// Registers the sqlite3 driver.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+/*
+Store keeps the results of fly runs in one sqlite file, so years and
+scenarios can be joined and filtered with SQL instead of walking the csv
+directories. Rows of a run are keyed by its scenario, e.g. "2019" or
+"2019-no-connections", rerunning a scenario replaces the artist's rows.
+
+Artists are keyed by output.ArtistID, venues by their venue registry ID and
+airports by IATA code. Events, trips and emissions are per scenario and
+artist, events numbered within their day by day_seq and emissions pointing
+at the trip they were worked out for by trip_seq.
+*/
+type Store interface {
+	PutVenues([]venue.Venue) error
+	// PutArtist replaces everything stored for the artist in the scenario.
+	PutArtist(scenario string, a ra.Artist, events crawler.Events, trips flight.Trips, outputs []atmos.Output) error
+	// Flights of every artist in the scenario, keyed by name.
+	Flights(scenario string) (map[string][]output.Flight, error)
+	// Query runs a read-only statement, returning its columns and rows as text.
+	Query(query string, args ...interface{}) ([]string, [][]string, error)
+	Close() error
+}
+
+const schema = `
+CREATE TABLE IF NOT EXISTS artists (
+	artist_id    TEXT PRIMARY KEY,
+	name         TEXT NOT NULL,
+	city         TEXT,
+	country      TEXT,
+	home_airport TEXT,
+	members      INTEGER,
+	crew         INTEGER,
+	events_total INTEGER,
+	excluded     TEXT
+);
+CREATE TABLE IF NOT EXISTS venues (
+	venue_id     TEXT PRIMARY KEY,
+	name         TEXT,
+	address      TEXT,
+	lat          REAL,
+	lng          REAL,
+	air_code     TEXT,
+	city         TEXT,
+	country      TEXT,
+	country_code TEXT,
+	time_zone    TEXT,
+	source       TEXT
+);
+CREATE TABLE IF NOT EXISTS airports (
+	code         TEXT PRIMARY KEY,
+	city         TEXT,
+	country      TEXT,
+	country_code TEXT,
+	time_zone    TEXT
+);
+CREATE TABLE IF NOT EXISTS events (
+	scenario     TEXT NOT NULL,
+	artist_id    TEXT NOT NULL REFERENCES artists,
+	date         TEXT NOT NULL,
+	day_seq      INTEGER NOT NULL,
+	title        TEXT,
+	venue        TEXT,
+	venue_id     TEXT,
+	location     TEXT,
+	city         TEXT,
+	country      TEXT,
+	country_code TEXT,
+	air_code     TEXT,
+	lat          REAL,
+	lng          REAL,
+	source       TEXT,
+	flags        TEXT,
+	failure      TEXT,
+	PRIMARY KEY (scenario, artist_id, date, day_seq)
+);
+CREATE TABLE IF NOT EXISTS trips (
+	scenario     TEXT NOT NULL,
+	artist_id    TEXT NOT NULL REFERENCES artists,
+	seq          INTEGER NOT NULL,
+	departed_at  TEXT,
+	departure    TEXT,
+	arrival      TEXT,
+	via          TEXT,
+	tour         TEXT,
+	passengers   INTEGER,
+	from_date    TEXT,
+	to_date      TEXT,
+	PRIMARY KEY (scenario, artist_id, seq)
+);
+CREATE TABLE IF NOT EXISTS emissions (
+	scenario             TEXT NOT NULL,
+	artist_id            TEXT NOT NULL REFERENCES artists,
+	seq                  INTEGER NOT NULL,
+	trip_seq             INTEGER,
+	schema_version       INTEGER,
+	leg_type             TEXT,
+	date                 TEXT,
+	departure            TEXT,
+	arrival              TEXT,
+	via                  TEXT,
+	passengers           INTEGER,
+	carbon_kg            REAL,
+	carbon_per_person_kg REAL,
+	fuel_l               REAL,
+	distance_km          INTEGER,
+	offset_eur           REAL,
+	tour                 TEXT,
+	from_date            TEXT,
+	from_event           TEXT,
+	from_venue           TEXT,
+	to_date              TEXT,
+	to_event             TEXT,
+	to_venue             TEXT,
+	methodology          TEXT,
+	PRIMARY KEY (scenario, artist_id, seq)
+);
+CREATE INDEX IF NOT EXISTS emissions_artist ON emissions (artist_id);
+CREATE INDEX IF NOT EXISTS events_venue ON events (venue_id);
+`
+
+// Open creates the file and its tables if they do not exist yet.
+func Open(fName string) (Store, error) {
+	db, err := sql.Open("sqlite3", fName+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	// One writer at a time, sqlite locks the whole file anyway
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", fName, err)
+	}
+	return service{db: db}, nil
+}
+
+// OpenReadOnly opens an existing file for reading, sqlite refusing any
+// statement that would write to it.
+func OpenReadOnly(fName string) (Store, error) {
+	if _, err := os.Stat(fName); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite3", "file:"+fName+"?mode=ro&_busy_timeout=5000")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %v", fName, err)
+	}
+	return service{db: db}, nil
+}
+
+type service struct {
+	db *sql.DB
+}
+
+func (s service) Close() error { return s.db.Close() }
+
+func (s service) PutVenues(venues []venue.Venue) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.Prepare(`INSERT OR REPLACE INTO venues
+		(venue_id, name, address, lat, lng, air_code, city, country, country_code, time_zone, source)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, v := range venues {
+		if _, err := stmt.Exec(v.ID, v.Name, v.Address, v.Lat, v.Lng, v.AirCode, v.City, v.Country, v.CountryCode, v.TimeZone, string(v.Source)); err != nil {
+			return fmt.Errorf("venue %s: %v", v.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s service) PutArtist(scenario string, a ra.Artist, events crawler.Events, trips flight.Trips, outputs []atmos.Output) error {
+	id := output.ArtistID(a.Name)
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	exec := func(query string, args ...interface{}) {
+		if err != nil {
+			return
+		}
+		_, err = tx.Exec(query, args...)
+	}
+
+	exec(`INSERT OR REPLACE INTO artists
+		(artist_id, name, city, country, home_airport, members, crew, events_total, excluded)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, a.Name, a.City, a.Country, a.AirCode, a.Members, a.Crew, a.EventsTotal, string(a.Excluded))
+	for _, table := range []string{"events", "trips", "emissions"} {
+		exec("DELETE FROM "+table+" WHERE scenario = ? AND artist_id = ?", scenario, id)
+	}
+
+	// Gigs on the same day numbered in the order they are listed for it
+	for _, date := range events.Dates() {
+		for daySeq, e := range events[date] {
+			exec(`INSERT INTO events
+				(scenario, artist_id, date, day_seq, title, venue, venue_id, location, city, country, country_code, air_code, lat, lng, source, flags, failure)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				scenario, id, date.Format("2006-01-02"), daySeq, e.Title, e.Venue, e.VenueID, e.Location, e.City, e.Country, e.CountryCode,
+				e.AirCode, e.Lat, e.Lng, e.Source, strings.Join(e.Flags, ";"), e.Failure)
+			if e.AirCode != "" {
+				exec(`INSERT OR IGNORE INTO airports (code, city, country, country_code, time_zone) VALUES (?, ?, ?, ?, ?)`,
+					e.AirCode, e.City, e.Country, e.CountryCode, e.TimeZone)
+			}
+		}
+	}
+	if a.AirCode != "" {
+		exec(`INSERT OR IGNORE INTO airports (code, city, country) VALUES (?, ?, ?)`, a.AirCode, a.City, a.Country)
+	}
+
+	// Calculate returns an output per trip that had both airports, in order,
+	// matched to them by route and date
+	next := 0
+	for seq, trip := range trips {
+		exec(`INSERT INTO trips
+			(scenario, artist_id, seq, departed_at, departure, arrival, via, tour, passengers, from_date, to_date)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			scenario, id, seq, trip.Date.Format(time.RFC3339), trip.DepCode, trip.ArrCode, trip.Via, trip.Tour, trip.Passengers,
+			gigDate(trip.From), gigDate(trip.To))
+		if trip.DepCode == "" || trip.ArrCode == "" || next >= len(outputs) {
+			continue
+		}
+		putEmissions(exec, scenario, id, next, seq, output.NewFlight(a.Name, outputs[next]))
+		next++
+	}
+	// Outputs without trips, e.g. from an artifact written before trips were kept
+	for ; next < len(outputs); next++ {
+		putEmissions(exec, scenario, id, next, nil, output.NewFlight(a.Name, outputs[next]))
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", a.Name, err)
+	}
+	return tx.Commit()
+}
+
+func putEmissions(exec func(string, ...interface{}), scenario, id string, seq int, tripSeq interface{}, f output.Flight) {
+	exec(`INSERT INTO emissions
+		(scenario, artist_id, seq, trip_seq, schema_version, leg_type, date, departure, arrival, via, passengers,
+		carbon_kg, carbon_per_person_kg, fuel_l, distance_km, offset_eur, tour,
+		from_date, from_event, from_venue, to_date, to_event, to_venue, methodology)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		scenario, id, seq, tripSeq, f.SchemaVersion, string(f.Leg), f.Date, f.Departure, f.Arrival, f.Via, f.Passengers,
+		f.CarbonKg, f.CarbonPerPersonKg, f.FuelL, f.DistanceKm, f.OffsetEUR, f.Tour,
+		f.FromDate, f.FromEvent, f.FromVenue, f.ToDate, f.ToEvent, f.ToVenue, f.Methodology)
+}
+
+func gigDate(gig flight.Gig) string {
+	if gig.IsZero() {
+		return ""
+	}
+	return gig.Date.Format("2006-01-02")
+}
+
+func (s service) Flights(scenario string) (map[string][]output.Flight, error) {
+	rows, err := s.db.Query(`SELECT
+		e.schema_version, e.artist_id, a.name, e.leg_type, e.date, e.departure, e.arrival, e.via, e.passengers,
+		e.carbon_kg, e.carbon_per_person_kg, e.fuel_l, e.distance_km, e.offset_eur, e.tour,
+		e.from_date, e.from_event, e.from_venue, e.to_date, e.to_event, e.to_venue, e.methodology
+		FROM emissions e JOIN artists a USING (artist_id)
+		WHERE e.scenario = ? ORDER BY a.name, e.seq`, scenario)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	flights := make(map[string][]output.Flight)
+	for rows.Next() {
+		var f output.Flight
+		var leg string
+		err := rows.Scan(&f.SchemaVersion, &f.ArtistID, &f.Artist, &leg, &f.Date, &f.Departure, &f.Arrival, &f.Via, &f.Passengers,
+			&f.CarbonKg, &f.CarbonPerPersonKg, &f.FuelL, &f.DistanceKm, &f.OffsetEUR, &f.Tour,
+			&f.FromDate, &f.FromEvent, &f.FromVenue, &f.ToDate, &f.ToEvent, &f.ToVenue, &f.Methodology)
+		if err != nil {
+			return flights, err
+		}
+		f.Leg = output.Leg(leg)
+		flights[f.Artist] = append(flights[f.Artist], f)
+	}
+	return flights, rows.Err()
+}
+
+func (s service) Query(query string, args ...interface{}) ([]string, [][]string, error) {
+	// Rolled back whatever it is, so a stray UPDATE changes nothing
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var records [][]string
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return columns, records, err
+		}
+		record := make([]string, len(columns))
+		for i, v := range values {
+			record[i] = v.String
+		}
+		records = append(records, record)
+	}
+	return columns, records, rows.Err()
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cleanscene.flights/lib/crawler"
+	"github.com/cleanscene.flights/lib/event"
+	"github.com/cleanscene.flights/lib/ra"
+)
+
+func tempFile(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "flights.db")
+}
+
+func doubleHeader() crawler.Events {
+	events := make(crawler.Events)
+	day := time.Date(2019, 7, 6, 0, 0, 0, 0, time.UTC)
+	events.Add(day, event.Event{Title: "Melt", AirCode: "TXL"})
+	events.Add(day, event.Event{Title: "Tresor", AirCode: "TXL"})
+	return events
+}
+
+func TestPutArtistDoubleHeader(t *testing.T) {
+	fName := tempFile(t)
+	db, err := Open(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Twice, the second run replacing the first
+	for i := 0; i < 2; i++ {
+		if err := db.PutArtist("2019", ra.Artist{Name: "Tiga"}, doubleHeader(), nil, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_, rows, err := db.Query("SELECT date, day_seq, title FROM events ORDER BY date, day_seq")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(rows))
+	for i, row := range rows {
+		got[i] = strings.Join(row, " ")
+	}
+	want := "2019-07-06 0 Melt, 2019-07-06 1 Tresor"
+	if strings.Join(got, ", ") != want {
+		t.Errorf("got %s, want %s", strings.Join(got, ", "), want)
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	fName := tempFile(t)
+	if _, err := OpenReadOnly(fName); err == nil {
+		t.Fatal("opened a missing file")
+	}
+	db, err := Open(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.PutArtist("2019", ra.Artist{Name: "Tiga"}, doubleHeader(), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	ro, err := OpenReadOnly(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ro.Close()
+	if _, rows, err := ro.Query("SELECT count(*) FROM events"); err != nil || rows[0][0] != "2" {
+		t.Errorf("got %v %v, want 2 events", rows, err)
+	}
+	if _, _, err := ro.Query("DELETE FROM events"); err == nil || !strings.Contains(err.Error(), "readonly") {
+		t.Errorf("got %v, want a read-only error", err)
+	}
+}
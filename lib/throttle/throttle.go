@@ -0,0 +1,125 @@
+package throttle
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Client is the part of *http.Client the upstream services use, so either
+// can be passed in.
+type Client interface {
+	Do(*http.Request) (*http.Response, error)
+}
+
+// OrDefault falls back to the plain http client when none is given.
+func OrDefault(c Client) Client {
+	if c == nil {
+		return http.DefaultClient
+	}
+	return c
+}
+
+// Get is http.Get with a context and a client.
+func Get(ctx context.Context, c Client, url string) (*http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return OrDefault(c).Do(req.WithContext(ctx))
+}
+
+/*
+New returns a client for one upstream that has at most concurrency requests
+in flight, a request holding its slot until its body is closed, and starts
+at most perSecond requests a second to any one host. Zero leaves either
+unlimited.
+*/
+func New(concurrency int, perSecond float64) Client {
+	t := &throttled{
+		client: &http.Client{Timeout: time.Minute},
+		next:   make(map[string]time.Time),
+	}
+	if concurrency > 0 {
+		t.slots = make(chan struct{}, concurrency)
+	}
+	if perSecond > 0 {
+		t.interval = time.Duration(float64(time.Second) / perSecond)
+	}
+	return t
+}
+
+type throttled struct {
+	client   *http.Client
+	slots    chan struct{}
+	interval time.Duration
+
+	mu   sync.Mutex
+	next map[string]time.Time
+}
+
+func (t *throttled) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	if t.slots != nil {
+		select {
+		case t.slots <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if err := t.wait(ctx, req.URL.Host); err != nil {
+		t.release()
+		return nil, err
+	}
+	resp, err := t.client.Do(req)
+	if err != nil {
+		t.release()
+		return resp, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: t.release}
+	return resp, nil
+}
+
+// Waits for the host's next turn, booking the one after for the next caller.
+func (t *throttled) wait(ctx context.Context, host string) error {
+	if t.interval == 0 {
+		return nil
+	}
+	t.mu.Lock()
+	now := time.Now()
+	at := t.next[host]
+	if at.Before(now) {
+		at = now
+	}
+	t.next[host] = at.Add(t.interval)
+	t.mu.Unlock()
+
+	timer := time.NewTimer(at.Sub(now))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *throttled) release() {
+	if t.slots != nil {
+		<-t.slots
+	}
+}
+
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (r *releaseOnClose) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(r.release)
+	return err
+}
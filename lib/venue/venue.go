@@ -0,0 +1,173 @@
+package venue
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Where a venue's details came from.
+type Source string
+
+const (
+	RA       Source = "ra"       // scraped from the RA club page
+	Geocoded Source = "geocoded" // coordinates and airport looked up from the address
+	Manual   Source = "manual"   // corrected by hand, never overwritten
+)
+
+// Venue is a club as RA lists it, with where it is and the airport serving it.
+type Venue struct {
+	ID      string  `json:"id"`
+	Name    string  `json:"name,omitempty"`
+	Address string  `json:"address,omitempty"`
+	Lat     float64 `json:"lat,omitempty"`
+	Lng     float64 `json:"lng,omitempty"`
+	// The resolved airport, with the city, country and time zone it gives
+	// the venue's gigs.
+	AirCode     string `json:"air_code,omitempty"`
+	City        string `json:"city,omitempty"`
+	Country     string `json:"country,omitempty"`
+	CountryCode string `json:"country_code,omitempty"`
+	TimeZone    string `json:"time_zone,omitempty"`
+	Source      Source `json:"source,omitempty"`
+}
+
+// HasCoords is true once the venue has been geocoded.
+func (v Venue) HasCoords() bool { return v.Lat != 0 || v.Lng != 0 }
+
+// Registry caches venues across runs, keyed by RA club ID.
+type Registry interface {
+	Get(string) (Venue, bool)
+	// Put records scraped or looked up details, manual entries only have
+	// their gaps filled.
+	Put(Venue)
+	// All venues, by ID.
+	All() []Venue
+	Save() error
+}
+
+/*
+Load reads the venue registry from a json file, starting empty if there is
+none yet. Corrections are made by editing an entry and setting its source to
+"manual", e.g.
+
+	{"id": "5031", "name": "Berghain", "lat": 52.511, "lng": 13.443, "air_code": "BER", "source": "manual"}
+*/
+func Load(fname string) (Registry, error) {
+	reg := &registry{fname: fname, venues: make(map[string]Venue)}
+	data, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return reg, nil
+	}
+	if err != nil {
+		return reg, err
+	}
+	var venues []Venue
+	if err := json.Unmarshal(data, &venues); err != nil {
+		return reg, err
+	}
+	for _, v := range venues {
+		reg.venues[v.ID] = v
+	}
+	return reg, nil
+}
+
+type registry struct {
+	mu     sync.Mutex
+	fname  string
+	venues map[string]Venue
+}
+
+func (r *registry) Get(id string) (Venue, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v, ok := r.venues[id]
+	return v, ok
+}
+
+func (r *registry) Put(v Venue) {
+	if v.ID == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	old, ok := r.venues[v.ID]
+	if !ok {
+		r.venues[v.ID] = v
+		return
+	}
+	if old.Source == Manual {
+		r.venues[v.ID] = fill(old, v)
+		return
+	}
+	r.venues[v.ID] = fill(v, old)
+}
+
+// Keeps the fields of the first venue, filling in what it lacks.
+func fill(v, other Venue) Venue {
+	set := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	set(&v.Name, other.Name)
+	set(&v.Address, other.Address)
+	set(&v.AirCode, other.AirCode)
+	set(&v.City, other.City)
+	set(&v.Country, other.Country)
+	set(&v.CountryCode, other.CountryCode)
+	set(&v.TimeZone, other.TimeZone)
+	set((*string)(&v.Source), string(other.Source))
+	if !v.HasCoords() {
+		v.Lat, v.Lng = other.Lat, other.Lng
+	}
+	return v
+}
+
+func (r *registry) All() []Venue {
+	r.mu.Lock()
+	var venues []Venue
+	for _, v := range r.venues {
+		venues = append(venues, v)
+	}
+	r.mu.Unlock()
+	sort.Slice(venues, func(i, j int) bool {
+		return venues[i].ID < venues[j].ID
+	})
+	return venues
+}
+
+// Written next to the registry and moved over it, so a failed save leaves
+// the venues resolved so far as they were.
+func (r *registry) Save() error {
+	data, err := json.MarshalIndent(r.All(), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := r.fname + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.fname)
+}
+
+// ClubID takes the RA club ID from a club link, either the old
+// /club.aspx?id=5031 or /clubs/5031.
+func ClubID(href string) string {
+	u, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	if id := u.Query().Get("id"); id != "" {
+		return id
+	}
+	if strings.Contains(u.Path, "/club") {
+		return path.Base(strings.TrimSuffix(u.Path, "/"))
+	}
+	return ""
+}
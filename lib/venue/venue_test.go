@@ -0,0 +1,101 @@
+package venue
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func tempFile(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "venue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "venues.json")
+}
+
+var (
+	manual  = Venue{ID: "5031", Name: "Berghain", Lat: 52.511, Lng: 13.443, AirCode: "BER", Source: Manual}
+	scraped = Venue{ID: "5031", Name: "Berghain / Panorama Bar", Address: "Am Wriezener Bahnhof, Berlin", Lat: 52.4, Lng: 13.5,
+		AirCode: "TXL", City: "Berlin", Country: "Germany", CountryCode: "DE", TimeZone: "Europe/Berlin", Source: Geocoded}
+)
+
+func TestPut(t *testing.T) {
+	tests := []struct {
+		name  string
+		first Venue
+		then  Venue
+		want  Venue
+	}{
+		{
+			name: "manual entries only have their gaps filled", first: manual, then: scraped,
+			want: Venue{ID: "5031", Name: "Berghain", Address: "Am Wriezener Bahnhof, Berlin", Lat: 52.511, Lng: 13.443,
+				AirCode: "BER", City: "Berlin", Country: "Germany", CountryCode: "DE", TimeZone: "Europe/Berlin", Source: Manual},
+		},
+		{
+			name: "manual coordinates are kept", first: Venue{ID: "5031", Address: "Berghain, Berlin", Source: Manual},
+			then: scraped,
+			want: Venue{ID: "5031", Name: "Berghain / Panorama Bar", Address: "Berghain, Berlin", Lat: 52.4, Lng: 13.5,
+				AirCode: "TXL", City: "Berlin", Country: "Germany", CountryCode: "DE", TimeZone: "Europe/Berlin", Source: Manual},
+		},
+		{
+			name: "newer lookups replace older ones", first: Venue{ID: "5031", Name: "Berghain", Address: "Berlin", Source: RA},
+			then: scraped, want: scraped,
+		},
+		{
+			name: "newer lookups keep what they lack", first: scraped,
+			then: Venue{ID: "5031", Name: "Berghain", Source: RA},
+			want: Venue{ID: "5031", Name: "Berghain", Address: "Am Wriezener Bahnhof, Berlin", Lat: 52.4, Lng: 13.5,
+				AirCode: "TXL", City: "Berlin", Country: "Germany", CountryCode: "DE", TimeZone: "Europe/Berlin", Source: RA},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reg, err := Load(tempFile(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			reg.Put(tt.first)
+			reg.Put(tt.then)
+			got, ok := reg.Get("5031")
+			if !ok || !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Manual corrections survive a save and a later run looking the venue up again.
+func TestSaveKeepsManual(t *testing.T) {
+	fName := tempFile(t)
+	reg, err := Load(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.Put(manual)
+	reg.Put(Venue{ID: "1", Name: "Tresor"})
+	if err := reg.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	reg, err = Load(fName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.Put(scraped)
+	got, _ := reg.Get("5031")
+	if got.AirCode != "BER" || got.Lat != manual.Lat || got.Name != manual.Name || got.Source != Manual {
+		t.Errorf("manual entry overwritten: %+v", got)
+	}
+	if len(reg.All()) != 2 {
+		t.Errorf("got %d venues, want 2", len(reg.All()))
+	}
+	files, _ := ioutil.ReadDir(filepath.Dir(fName))
+	if len(files) != 1 {
+		t.Errorf("got %d files after saving, want only the registry", len(files))
+	}
+}